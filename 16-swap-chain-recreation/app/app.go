@@ -1,7 +1,14 @@
 package app
 
 import (
-	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+	"time"
+	"unsafe"
+	"vulkan-tutorial-go/16-swap-chain-recreation/memory"
+	"vulkan-tutorial-go/16-swap-chain-recreation/shaders"
+	"vulkan-tutorial-go/16-swap-chain-recreation/vkext"
 
 	"github.com/go-gl/glfw/v3.3/glfw"
 	vk "github.com/vulkan-go/vulkan"
@@ -11,10 +18,19 @@ const width = 800
 const height = 600
 const maxFramesInFlight = 2
 
+// pausedWaitTimeout bounds how long mainLoop blocks on window events while
+// the surface is zero-sized, so OnUpdate keeps ticking when minimized.
+const pausedWaitTimeout = 1.0 / 60
+
+const defaultFixedTimestep = time.Second / 60
+
 type app struct {
 	window                   *glfw.Window
 	physicalDevice           vk.PhysicalDevice
 	instance                 vk.Instance
+	instanceFuncs            *vkext.Instance
+	deviceFuncs              *vkext.Device
+	dynamicRendering         bool
 	config                   AppConfig
 	debugMessenger           vk.DebugReportCallback
 	logicalDevice            vk.Device
@@ -27,47 +43,221 @@ type app struct {
 	swapChainImageFormat     vk.Format
 	swapChainImageViews      []vk.ImageView
 	renderPass               vk.RenderPass
+	descriptorSetLayouts     []vk.DescriptorSetLayout
 	pipelineLayout           vk.PipelineLayout
 	graphicsPipeline         vk.Pipeline
 	swapChainFrameBuffers    []vk.Framebuffer
-	commandPool              vk.CommandPool
+	commandPools             []vk.CommandPool
 	commandBuffers           []vk.CommandBuffer
+	recordWorkers            []*recordWorker
 	imageAvailableSemaphores []vk.Semaphore
 	renderFinishedSemaphores []vk.Semaphore
-	inFlightFences           []vk.Fence
-	imagesInFlight           []vk.Fence
+	timeline                 *gpuTimeline
+	imagesInFlight           []uint64
 	currentFrame             int
 	frameBufferResized       bool
+	paused                   bool
+	windowMode               WindowMode
+	windowedRect             windowedRect
+	input                    *Input
+	camera                   Camera
+	clock                    *FrameClock
+	instanceAPIVersion       uint32
+	apiVersion               uint32
+	enabledFeatures          vk.PhysicalDeviceFeatures
+	enabledFeatures2         vkext.Features
+	instanceExtensions       []string
+	instanceLayers           []string
+	deviceExtensions         []string
+	deletionQueue            deletionQueue
+	renderPassDeletionQueue  deletionQueue
+	pipelineDeletionQueue    deletionQueue
+	swapChainDeletionQueue   deletionQueue
+	leaks                    *leakTracker
+	allocator                *memory.Allocator
+	lastMemoryReport         float64
+	shaderWatcher            *shaderWatcher
 }
 
+// RecordFunc records draw commands for one frame into commandBuffer.
+// framebuffer is vk.NullFramebuffer when the frame uses dynamic rendering.
+type RecordFunc func(commandBuffer vk.CommandBuffer, framebuffer vk.Framebuffer, extent vk.Extent2D) error
+
 type AppConfig struct {
-	EnableValidationLayers   bool
-	ValidationLayers         []string
-	RequiredDeviceExtensions []string
+	// ApplicationName, ApplicationVersion, EngineName and EngineVersion are
+	// reported to the driver through vk.ApplicationInfo, drivers key app
+	// profiles on them. Versions are made with vk.MakeVersion.
+	ApplicationName    string
+	ApplicationVersion uint32
+	EngineName         string
+	EngineVersion      uint32
+
+	// ValidationLayers are required when EnableValidationLayers is set.
+	// OptionalLayers are enabled when they are installed.
+	EnableValidationLayers bool
+	ValidationLayers       []string
+	OptionalLayers         []string
+	// Required extensions must be supported or initialization fails listing
+	// every missing one. Optional extensions are enabled when supported, see
+	// HasInstanceExtension and HasDeviceExtension.
+	RequiredInstanceExtensions []string
+	OptionalInstanceExtensions []string
+	RequiredDeviceExtensions   []string
+	OptionalDeviceExtensions   []string
+	// DisablePortabilityEnumeration hides portability-subset implementations
+	// such as MoltenVK. By default VK_KHR_portability_enumeration is enabled
+	// when the loader has it, and VK_KHR_portability_subset is enabled on
+	// every device that advertises it.
+	DisablePortabilityEnumeration bool
+	// APIVersion is the Vulkan version requested from the instance, made
+	// with vk.MakeVersion. Defaults to 1.0. If the loader, asked with
+	// vkEnumerateInstanceVersion, supports an older version the instance
+	// falls back to it, as long as MinAPIVersion allows.
+	APIVersion uint32
+	// MinAPIVersion is the lowest Vulkan version the instance and the
+	// physical device must support.
+	MinAPIVersion uint32
+	// RequiredFeatures must all be supported by the physical device.
+	// OptionalFeatures are enabled when the device supports them.
+	RequiredFeatures vk.PhysicalDeviceFeatures
+	OptionalFeatures vk.PhysicalDeviceFeatures
+	// RequiredFeatures2 and OptionalFeatures2 do the same for the features
+	// Vulkan 1.1 to 1.3 added, queried and enabled through a pNext chain. A
+	// feature is only supported when both APIVersion and the device reach
+	// the version that added it, or the device has an extension providing
+	// it, see vkext.Features. Required features that aren't fail device
+	// selection with ErrMissingFeatures.
+	RequiredFeatures2 vkext.Features
+	OptionalFeatures2 vkext.Features
+	Window            WindowConfig
+	// Record is called every frame inside the render pass, with the graphics
+	// pipeline bound, to record the frame's draw commands. When nil the
+	// triangle is drawn.
+	Record RecordFunc
+	// RecordWorkers is the number of goroutines recording secondary command
+	// buffers with RecordSecondary in parallel. When both are set they
+	// replace Record.
+	RecordWorkers   int
+	RecordSecondary SecondaryRecordFunc
+	// Camera is updated from window input every frame and receives the
+	// swapchain aspect ratio whenever the swapchain is recreated.
+	Camera Camera
+	// OnUpdate is called once per main loop iteration with the frame delta
+	// in seconds, including while rendering is paused because the window is
+	// minimized.
+	OnUpdate func(dt float64) error
+	// OnFixedUpdate is called zero or more times per frame so that it runs
+	// FixedTimestep apart on average, independent of the frame rate.
+	OnFixedUpdate func(dt float64) error
+	// FixedTimestep is the interval of OnFixedUpdate. Defaults to 1/60s.
+	FixedTimestep time.Duration
+	// MaxFPS caps the frame rate by sleeping at the start of a frame. 0
+	// leaves the frame rate uncapped.
+	MaxFPS float64
+	// TrackLeaks records the creating call stack of every Vulkan handle and
+	// logs the handles still alive after cleanup, and those destroyed twice.
+	// It costs a stack capture per handle, leave it off in release builds.
+	TrackLeaks bool
+	// Memory configures the device memory allocator, see Allocator.
+	Memory memory.Config
+	// ShaderSearchPaths are directories searched, in order, for the
+	// compiled SPIR-V before ShaderFS. Set it to pick up recompiled shaders
+	// without rebuilding.
+	ShaderSearchPaths []string
+	// ShaderFS holds the compiled SPIR-V shipped with the binary. Defaults
+	// to the chapter's embedded shaders.
+	ShaderFS fs.FS
+	// ShaderCompiler, when set, compiles shader.vert and shader.frag found
+	// on ShaderSearchPaths instead of loading the precompiled SPIR-V. If
+	// compiling fails the error is logged and the precompiled SPIR-V used.
+	ShaderCompiler *shaders.Compiler
+	// VertexShader and FragmentShader select the module, entry point and
+	// specialization constants of each stage of the graphics pipeline. The
+	// modules default to vert.spv and frag.spv.
+	VertexShader   ShaderStageConfig
+	FragmentShader ShaderStageConfig
+	// HotReloadShaders watches ShaderSearchPaths and rebuilds the graphics
+	// pipeline between frames when a shader in them changes. A shader that
+	// fails to compile is reported and the running pipeline kept.
+	HotReloadShaders bool
+	// MemoryReportInterval is how often the budget and usage of each memory
+	// heap is logged, see MemoryBudget. 0 disables the report.
+	MemoryReportInterval time.Duration
 }
 
 func New(config AppConfig) *app {
-	app := &app{config: config}
+	if config.Window.Width == 0 {
+		config.Window.Width = width
+	}
+	if config.Window.Height == 0 {
+		config.Window.Height = height
+	}
+	if config.Window.Title == "" {
+		config.Window.Title = strings.TrimSuffix(config.ApplicationName, "\x00")
+	}
+	if config.Window.Title == "" {
+		config.Window.Title = "Vulkan"
+	}
+
+	if config.ApplicationName == "" {
+		config.ApplicationName = "Hello Triangle"
+	}
+	if config.ApplicationVersion == 0 {
+		config.ApplicationVersion = vk.MakeVersion(1, 0, 0)
+	}
+	if config.EngineName == "" {
+		config.EngineName = "No Engine"
+	}
+	if config.EngineVersion == 0 {
+		config.EngineVersion = vk.MakeVersion(1, 0, 0)
+	}
+
+	if config.APIVersion == 0 {
+		config.APIVersion = vk.MakeVersion(1, 0, 0)
+	}
+	if config.APIVersion < config.MinAPIVersion {
+		config.APIVersion = config.MinAPIVersion
+	}
+
+	if config.ShaderFS == nil {
+		config.ShaderFS = shaders.FS
+	}
+	config.VertexShader.setDefaults("vert.spv")
+	config.FragmentShader.setDefaults("frag.spv")
+
+	if config.FixedTimestep <= 0 {
+		config.FixedTimestep = defaultFixedTimestep
+	}
+
+	app := &app{config: config, camera: config.Camera}
+	if config.TrackLeaks {
+		app.leaks = newLeakTracker()
+	}
 	return app
 }
 
 func (a *app) Run() error {
+	// Every created object registers its destroy call, so this also tears
+	// down a partially initialized app.
+	defer a.cleanup()
+
 	var err error
 	err = a.initWindow()
 	if err != nil {
-		return err
+		return withStage(StageWindow, err)
 	}
 
 	err = a.initVulkan()
 	if err != nil {
+		a.checkDeviceLost(err)
 		return err
 	}
 
 	err = a.mainLoop()
 	if err != nil {
+		a.checkDeviceLost(err)
 		return err
 	}
-	a.cleanup()
 
 	return nil
 }
@@ -77,53 +267,231 @@ func (a *app) initWindow() error {
 	if err != nil {
 		return err
 	}
+	a.deletionQueue.push(glfw.Terminate)
+
+	cfg := a.config.Window
+	resizable := glfw.True
+	if cfg.NotResizable {
+		resizable = glfw.False
+	}
 
 	glfw.WindowHint(glfw.ClientAPI, glfw.NoAPI)
-	glfw.WindowHint(glfw.Resizable, glfw.True)
-	win, err := glfw.CreateWindow(width, height, "Vulkan", nil, nil)
+	glfw.WindowHint(glfw.Resizable, resizable)
+	win, err := glfw.CreateWindow(cfg.Width, cfg.Height, cfg.Title, nil, nil)
 	if err != nil {
 		return err
 	}
 
 	a.window = win
+	a.deletionQueue.push(win.Destroy)
+	a.input = newInput(win)
+	x, y := win.GetPos()
+	a.windowedRect = windowedRect{x: x, y: y, width: cfg.Width, height: cfg.Height}
+
+	if cfg.Mode != WindowModeWindowed {
+		err = a.applyWindowMode(cfg.Mode)
+		if err != nil {
+			return err
+		}
+	}
 
 	win.SetFramebufferSizeCallback(func(w *glfw.Window, width int, height int) {
 		a.frameBufferResized = true
+		if width == 0 || height == 0 {
+			a.paused = true
+		}
 	})
 
 	return nil
 }
 
+// Input returns the keyboard, mouse and gamepad state of the current frame.
+func (a *app) Input() *Input {
+	return a.input
+}
+
+// APIVersion is the Vulkan version usable with the instance and the selected
+// physical device.
+func (a *app) APIVersion() uint32 {
+	return a.apiVersion
+}
+
+// EnabledFeatures returns the device features the logical device was created
+// with.
+func (a *app) EnabledFeatures() vk.PhysicalDeviceFeatures {
+	return a.enabledFeatures
+}
+
+// EnabledFeatures2 returns the Vulkan 1.1 to 1.3 features the logical device
+// was created with.
+func (a *app) EnabledFeatures2() vkext.Features {
+	return a.enabledFeatures2
+}
+
+// EnabledInstanceExtensions returns the instance extensions the instance was
+// created with, including those GLFW requires.
+func (a *app) EnabledInstanceExtensions() []string {
+	return a.instanceExtensions
+}
+
+func (a *app) EnabledLayers() []string {
+	return a.instanceLayers
+}
+
+func (a *app) EnabledDeviceExtensions() []string {
+	return a.deviceExtensions
+}
+
+func (a *app) HasInstanceExtension(name string) bool {
+	return containsName(a.instanceExtensions, name)
+}
+
+func (a *app) HasDeviceExtension(name string) bool {
+	return containsName(a.deviceExtensions, name)
+}
+
+// Allocator sub-allocates device memory for buffers and images. It is
+// created with the logical device and destroyed in cleanup, free allocations
+// before that.
+func (a *app) Allocator() *memory.Allocator {
+	return a.allocator
+}
+
+// DescriptorSetLayouts returns the descriptor set layouts of the graphics
+// pipeline, indexed by set number. They are generated from the shaders and
+// replaced whenever the pipeline is rebuilt.
+func (a *app) DescriptorSetLayouts() []vk.DescriptorSetLayout {
+	return a.descriptorSetLayouts
+}
+
+// PipelineLayout returns the layout of the graphics pipeline, generated from
+// the descriptor sets and push constants of the shaders. A hot reload of the
+// shaders replaces it and destroys the old one, so look it up again each
+// frame instead of keeping it.
+func (a *app) PipelineLayout() vk.PipelineLayout {
+	return a.pipelineLayout
+}
+
+// Clock returns the frame clock. It is only valid once the main loop runs.
+func (a *app) Clock() *FrameClock {
+	return a.clock
+}
+
+func (a *app) Camera() Camera {
+	return a.camera
+}
+
+func (a *app) SetCamera(camera Camera) {
+	a.camera = camera
+	a.updateCameraAspect()
+}
+
+func (a *app) updateCameraAspect() {
+	if a.camera == nil || a.swapChainExtent.Height == 0 {
+		return
+	}
+
+	a.camera.SetAspect(float32(a.swapChainExtent.Width) / float32(a.swapChainExtent.Height))
+}
+
 func (a *app) mainLoop() error {
+	a.clock = newFrameClock(a.config.FixedTimestep, a.config.MaxFPS)
+	if a.config.HotReloadShaders {
+		if len(a.config.ShaderSearchPaths) == 0 {
+			log.Printf("[SHADER] hot reload is on but ShaderSearchPaths is empty, nothing to watch")
+		}
+		a.shaderWatcher = newShaderWatcher(a.config.ShaderSearchPaths)
+	}
 	for !a.window.ShouldClose() {
-		glfw.PollEvents()
-		err := a.drawFrame()
+		a.input.beginFrame()
+		if a.paused {
+			glfw.WaitEventsTimeout(pausedWaitTimeout)
+		} else {
+			glfw.PollEvents()
+		}
+		a.input.pollGamepads()
+
+		a.clock.tick()
+		dt := a.clock.DeltaTime()
+
+		if a.camera != nil {
+			a.camera.Update(a.input, dt)
+		}
+
+		for i := a.clock.fixedSteps(); i > 0 && a.config.OnFixedUpdate != nil; i-- {
+			err := a.config.OnFixedUpdate(a.clock.FixedTimestep())
+			if err != nil {
+				return err
+			}
+		}
+
+		if a.config.OnUpdate != nil {
+			err := a.config.OnUpdate(dt)
+			if err != nil {
+				return err
+			}
+		}
+
+		if a.paused {
+			w, h := a.window.GetFramebufferSize()
+			if w == 0 || h == 0 {
+				continue
+			}
+
+			a.paused = false
+			a.frameBufferResized = false
+			err := a.recreateSwapChain()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := a.reloadShaders()
 		if err != nil {
 			return err
 		}
-	}
 
-	vk.DeviceWaitIdle(a.logicalDevice)
+		err = a.drawFrame()
+		if err != nil {
+			return withStage(StageFrame, err)
+		}
+
+		a.reportMemory()
+	}
 
-	return nil
+	return vkErrorf(vk.DeviceWaitIdle(a.logicalDevice), "failed to wait for the device to go idle")
 }
 
 func (a *app) drawFrame() error {
 	var imageIndex uint32
-	vk.WaitForFences(a.logicalDevice, 1, []vk.Fence{a.inFlightFences[a.currentFrame]}, vk.True, vk.MaxUint64)
+	err := a.timeline.waitSlot(a.currentFrame)
+	if err != nil {
+		return err
+	}
 
 	res := vk.AcquireNextImage(a.logicalDevice, a.swapChain, vk.MaxUint64, a.imageAvailableSemaphores[a.currentFrame], vk.NullFence, &imageIndex)
 	if res == vk.ErrorOutOfDate {
 		return a.recreateSwapChain()
 	} else if res != vk.Success && res != vk.Suboptimal {
-		return fmt.Errorf("failed to acquire swapchain image")
+		return vkErrorf(res, "failed to acquire swapchain image")
+	}
+
+	err = a.timeline.wait(a.imagesInFlight[imageIndex])
+	if err != nil {
+		return err
 	}
 
-	if a.imagesInFlight[imageIndex] != vk.NullFence {
-		vk.WaitForFences(a.logicalDevice, 1, []vk.Fence{a.imagesInFlight[imageIndex]}, vk.True, vk.MaxUint64)
+	commandBuffer := a.commandBuffers[a.currentFrame]
+	err = vkError(vk.ResetCommandPool(a.logicalDevice, a.commandPools[a.currentFrame], 0))
+	if err != nil {
+		return err
 	}
 
-	a.imagesInFlight[imageIndex] = a.inFlightFences[a.currentFrame]
+	err = a.recordCommandBuffer(commandBuffer, imageIndex)
+	if err != nil {
+		return err
+	}
 
 	waitsemaphores := []vk.Semaphore{a.imageAvailableSemaphores[a.currentFrame]}
 	signalsemaphores := []vk.Semaphore{a.renderFinishedSemaphores[a.currentFrame]}
@@ -136,17 +504,23 @@ func (a *app) drawFrame() error {
 		PWaitSemaphores:      waitsemaphores,
 		PWaitDstStageMask:    waitStages,
 		CommandBufferCount:   1,
-		PCommandBuffers:      []vk.CommandBuffer{a.commandBuffers[imageIndex]},
+		PCommandBuffers:      []vk.CommandBuffer{commandBuffer},
 		SignalSemaphoreCount: uint32(len(signalsemaphores)),
 		PSignalSemaphores:    signalsemaphores,
 	}}
 
-	vk.ResetFences(a.logicalDevice, 1, []vk.Fence{a.inFlightFences[a.currentFrame]})
-	err := vk.Error(vk.QueueSubmit(a.graphicsQueue, 1, submitInfo, a.inFlightFences[a.currentFrame]))
+	fence, err := a.timeline.prepareSubmit(a.currentFrame, &submitInfo[0])
 	if err != nil {
 		return err
 	}
 
+	err = vkError(vk.QueueSubmit(a.graphicsQueue, 1, submitInfo, fence))
+	if err != nil {
+		return err
+	}
+
+	a.imagesInFlight[imageIndex] = a.timeline.signal(a.currentFrame)
+
 	presentInfo := vk.PresentInfo{
 		SType:              vk.StructureTypePresentInfo,
 		PNext:              nil,
@@ -163,7 +537,7 @@ func (a *app) drawFrame() error {
 		a.frameBufferResized = false
 		return a.recreateSwapChain()
 	} else if res != vk.Success {
-		return fmt.Errorf("failed to present swapchain image")
+		return vkErrorf(res, "failed to present swapchain image")
 	}
 
 	//vk.QueueWaitIdle(a.presentQueue)
@@ -174,80 +548,84 @@ func (a *app) drawFrame() error {
 }
 
 func (a *app) cleanup() {
+	// Nothing may be destroyed while the GPU still uses it. The result is
+	// ignored, a lost device returns at once and may still be torn down.
+	if unsafe.Pointer(a.logicalDevice) != vk.NullHandle {
+		vk.DeviceWaitIdle(a.logicalDevice)
+	}
+
 	a.cleanupSwapChain()
+	a.cleanupPipeline()
+	a.deletionQueue.flush()
 
-	for i := 0; i < maxFramesInFlight; i++ {
-		vk.DestroySemaphore(a.logicalDevice, a.renderFinishedSemaphores[i], nil)
-		vk.DestroySemaphore(a.logicalDevice, a.imageAvailableSemaphores[i], nil)
-		vk.DestroyFence(a.logicalDevice, a.inFlightFences[i], nil)
+	if leaked := a.leaks.report(); leaked > 0 {
+		log.Printf("[LEAK] %d Vulkan handles were not destroyed", leaked)
 	}
-	vk.DestroyCommandPool(a.logicalDevice, a.commandPool, nil)
+}
 
-	vk.DestroyDevice(a.logicalDevice, nil)
-	if a.config.EnableValidationLayers {
-		vk.DestroyDebugReportCallback(a.instance, a.debugMessenger, nil)
-	}
-	vk.DestroySurface(a.instance, a.windowSurface, nil)
-	vk.DestroyInstance(a.instance, nil)
-	a.window.Destroy()
-	glfw.Terminate()
+// cleanupPipeline destroys the render pass and the objects built on it. They
+// only depend on the swapchain image format, not its extent.
+func (a *app) cleanupPipeline() {
+	a.pipelineDeletionQueue.flush()
+	a.renderPassDeletionQueue.flush()
 }
 
 func (a *app) cleanupSwapChain() {
-	for _, v := range a.swapChainFrameBuffers {
-		vk.DestroyFramebuffer(a.logicalDevice, v, nil)
-	}
-
-	vk.FreeCommandBuffers(a.logicalDevice, a.commandPool, uint32(len(a.commandBuffers)), a.commandBuffers)
-
-	vk.DestroyPipeline(a.logicalDevice, a.graphicsPipeline, nil)
-	vk.DestroyPipelineLayout(a.logicalDevice, a.pipelineLayout, nil)
-	vk.DestroyRenderPass(a.logicalDevice, a.renderPass, nil)
-	for i := range a.swapChainImageViews {
-		vk.DestroyImageView(a.logicalDevice, a.swapChainImageViews[i], nil)
-	}
-	vk.DestroySwapchain(a.logicalDevice, a.swapChain, nil)
+	a.swapChainDeletionQueue.flush()
 }
 
+// recreateSwapChain rebuilds every swapchain dependent object. If the
+// framebuffer is zero-sized (the window is minimized) it only marks the app as
+// paused; mainLoop performs the rebuild once the window is restored.
 func (a *app) recreateSwapChain() error {
 	w, h := a.window.GetFramebufferSize()
-	for w == 0 || h == 0 {
-		w, h = a.window.GetFramebufferSize()
-		glfw.WaitEvents()
+	if w == 0 || h == 0 {
+		a.paused = true
+		return nil
 	}
 
-	vk.DeviceWaitIdle(a.logicalDevice)
+	err := vkErrorf(vk.DeviceWaitIdle(a.logicalDevice), "failed to wait for the device to go idle")
+	if err != nil {
+		return withStage(StageSwapChain, err)
+	}
 	a.cleanupSwapChain()
 
-	err := a.createSwapChain()
+	oldFormat := a.swapChainImageFormat
+	err = a.createSwapChain()
 	if err != nil {
-		return err
+		return withStage(StageSwapChain, err)
 	}
 
+	// The device is idle, nothing is in flight for the new images.
+	a.imagesInFlight = make([]uint64, len(a.swapChainImages))
+
 	err = a.createImageViews()
 	if err != nil {
-		return err
+		return withStage(StageImageViews, err)
 	}
 
-	err = a.createRenderPass()
-	if err != nil {
-		return err
-	}
+	// Viewport and scissor are dynamic, the render pass and pipeline only
+	// need rebuilding when the surface format changes.
+	if a.swapChainImageFormat != oldFormat {
+		a.cleanupPipeline()
 
-	err = a.createGraphicsPipeline()
-	if err != nil {
-		return err
+		err = a.createRenderPass()
+		if err != nil {
+			return withStage(StageRenderPass, err)
+		}
+
+		err = a.createGraphicsPipeline()
+		if err != nil {
+			return withStage(StagePipeline, err)
+		}
 	}
 
 	err = a.createFrameBuffers()
 	if err != nil {
-		return err
+		return withStage(StageFramebuffers, err)
 	}
 
-	err = a.createCommandBuffers()
-	if err != nil {
-		return err
-	}
+	a.updateCameraAspect()
 
 	return nil
 }
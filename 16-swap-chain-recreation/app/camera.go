@@ -0,0 +1,189 @@
+package app
+
+import (
+	"math"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// Camera produces the view and projection matrices for a frame. The app calls
+// Update once per main loop iteration and SetAspect whenever the swapchain
+// extent changes.
+type Camera interface {
+	Update(in *Input, dt float64)
+	SetAspect(aspect float32)
+	View() Mat4
+	Projection() Mat4
+}
+
+// Projection holds the perspective parameters shared by every camera.
+type Projection struct {
+	FovY   float32
+	Near   float32
+	Far    float32
+	Aspect float32
+}
+
+func defaultProjection() Projection {
+	return Projection{
+		FovY:   math.Pi / 4,
+		Near:   0.1,
+		Far:    100,
+		Aspect: float32(width) / float32(height),
+	}
+}
+
+func (p *Projection) SetAspect(aspect float32) {
+	p.Aspect = aspect
+}
+
+func (p *Projection) Projection() Mat4 {
+	return Perspective(p.FovY, p.Aspect, p.Near, p.Far)
+}
+
+const maxPitch = math.Pi/2 - 0.01
+
+func clampPitch(pitch float32) float32 {
+	if pitch > maxPitch {
+		return maxPitch
+	}
+	if pitch < -maxPitch {
+		return -maxPitch
+	}
+	return pitch
+}
+
+// OrbitCamera circles around Target. Dragging with the left mouse button
+// rotates, the middle button pans and the scroll wheel zooms.
+type OrbitCamera struct {
+	Projection
+	Target      Vec3
+	Distance    float32
+	Yaw         float32
+	Pitch       float32
+	Sensitivity float32
+	ZoomSpeed   float32
+}
+
+func NewOrbitCamera(target Vec3, distance float32) *OrbitCamera {
+	return &OrbitCamera{
+		Projection:  defaultProjection(),
+		Target:      target,
+		Distance:    distance,
+		Sensitivity: 0.005,
+		ZoomSpeed:   0.1,
+	}
+}
+
+func (c *OrbitCamera) eye() Vec3 {
+	cosPitch := float32(math.Cos(float64(c.Pitch)))
+	offset := Vec3{
+		cosPitch * float32(math.Sin(float64(c.Yaw))),
+		float32(math.Sin(float64(c.Pitch))),
+		cosPitch * float32(math.Cos(float64(c.Yaw))),
+	}
+	return c.Target.Add(offset.Mul(c.Distance))
+}
+
+func (c *OrbitCamera) Update(in *Input, dt float64) {
+	dx, dy := in.CursorDelta()
+
+	if in.MouseButtonDown(glfw.MouseButtonLeft) {
+		c.Yaw -= float32(dx) * c.Sensitivity
+		c.Pitch = clampPitch(c.Pitch + float32(dy)*c.Sensitivity)
+	}
+
+	if in.MouseButtonDown(glfw.MouseButtonMiddle) {
+		forward := c.Target.Sub(c.eye()).Normalize()
+		right := forward.Cross(Vec3{0, 1, 0}).Normalize()
+		up := right.Cross(forward)
+		scale := c.Distance * c.Sensitivity * 0.2
+		c.Target = c.Target.Add(right.Mul(-float32(dx) * scale)).Add(up.Mul(float32(dy) * scale))
+	}
+
+	_, scrollY := in.Scroll()
+	if scrollY != 0 {
+		c.Distance *= float32(math.Pow(float64(1-c.ZoomSpeed), scrollY))
+		if c.Distance < c.Near {
+			c.Distance = c.Near
+		}
+	}
+}
+
+func (c *OrbitCamera) View() Mat4 {
+	return LookAt(c.eye(), c.Target, Vec3{0, 1, 0})
+}
+
+// FlyCamera is a free-fly FPS-style camera. WASD moves, Space and Left Control
+// move up and down, Left Shift speeds up and the mouse looks around while the
+// cursor is captured (or the right mouse button is held).
+type FlyCamera struct {
+	Projection
+	Position    Vec3
+	Yaw         float32
+	Pitch       float32
+	Speed       float32
+	Sensitivity float32
+}
+
+func NewFlyCamera(position Vec3) *FlyCamera {
+	return &FlyCamera{
+		Projection:  defaultProjection(),
+		Position:    position,
+		Yaw:         math.Pi,
+		Speed:       2.5,
+		Sensitivity: 0.002,
+	}
+}
+
+func (c *FlyCamera) forward() Vec3 {
+	cosPitch := float32(math.Cos(float64(c.Pitch)))
+	return Vec3{
+		cosPitch * float32(math.Sin(float64(c.Yaw))),
+		float32(math.Sin(float64(c.Pitch))),
+		cosPitch * float32(math.Cos(float64(c.Yaw))),
+	}
+}
+
+func (c *FlyCamera) Update(in *Input, dt float64) {
+	if in.CursorCaptured() || in.MouseButtonDown(glfw.MouseButtonRight) {
+		dx, dy := in.CursorDelta()
+		c.Yaw -= float32(dx) * c.Sensitivity
+		c.Pitch = clampPitch(c.Pitch - float32(dy)*c.Sensitivity)
+	}
+
+	forward := c.forward()
+	right := forward.Cross(Vec3{0, 1, 0}).Normalize()
+	up := Vec3{0, 1, 0}
+
+	var move Vec3
+	if in.KeyDown(glfw.KeyW) {
+		move = move.Add(forward)
+	}
+	if in.KeyDown(glfw.KeyS) {
+		move = move.Sub(forward)
+	}
+	if in.KeyDown(glfw.KeyD) {
+		move = move.Add(right)
+	}
+	if in.KeyDown(glfw.KeyA) {
+		move = move.Sub(right)
+	}
+	if in.KeyDown(glfw.KeySpace) {
+		move = move.Add(up)
+	}
+	if in.KeyDown(glfw.KeyLeftControl) {
+		move = move.Sub(up)
+	}
+
+	speed := c.Speed
+	if in.KeyDown(glfw.KeyLeftShift) {
+		speed *= 4
+	}
+
+	c.Position = c.Position.Add(move.Normalize().Mul(speed * float32(dt)))
+}
+
+func (c *FlyCamera) View() Mat4 {
+	return LookAt(c.Position, c.Position.Add(c.forward()), Vec3{0, 1, 0})
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"time"
+)
+
+// maxFrameDelta clamps the delta of a single frame so a long stall (a debugger
+// break, dragging the window) doesn't make the fixed update loop try to catch
+// up on seconds of simulation at once.
+const maxFrameDelta = 250 * time.Millisecond
+
+// fpsSmoothing is the weight of the newest frame in the exponential moving
+// average used for FPS.
+const fpsSmoothing = 0.1
+
+// FrameClock measures frame times for the main loop and drives the fixed
+// timestep update.
+type FrameClock struct {
+	start       time.Time
+	last        time.Time
+	delta       time.Duration
+	fps         float64
+	frameCount  uint64
+	accumulator time.Duration
+	step        time.Duration
+	minFrame    time.Duration
+}
+
+func newFrameClock(fixedTimestep time.Duration, maxFPS float64) *FrameClock {
+	var minFrame time.Duration
+	if maxFPS > 0 {
+		minFrame = time.Duration(float64(time.Second) / maxFPS)
+	}
+
+	now := time.Now()
+	return &FrameClock{
+		start:    now,
+		last:     now,
+		step:     fixedTimestep,
+		minFrame: minFrame,
+	}
+}
+
+// tick starts a new frame, sleeping first if that is needed to respect the
+// frame rate cap.
+func (c *FrameClock) tick() {
+	if c.minFrame > 0 {
+		if wait := c.minFrame - time.Since(c.last); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+
+	now := time.Now()
+	c.delta = now.Sub(c.last)
+	c.last = now
+	c.frameCount++
+
+	if c.delta > 0 {
+		instant := float64(time.Second) / float64(c.delta)
+		if c.fps == 0 {
+			c.fps = instant
+		} else {
+			c.fps += (instant - c.fps) * fpsSmoothing
+		}
+	}
+
+	if c.step > 0 {
+		delta := c.delta
+		if delta > maxFrameDelta {
+			delta = maxFrameDelta
+		}
+		c.accumulator += delta
+	}
+}
+
+// fixedSteps returns how many fixed updates are due and consumes them from the
+// accumulator.
+func (c *FrameClock) fixedSteps() int {
+	if c.step <= 0 {
+		return 0
+	}
+
+	n := int(c.accumulator / c.step)
+	c.accumulator -= time.Duration(n) * c.step
+	return n
+}
+
+// DeltaTime is the time between the start of the previous frame and this one
+// in seconds.
+func (c *FrameClock) DeltaTime() float64 {
+	return c.delta.Seconds()
+}
+
+// Time is the number of seconds since the clock was started.
+func (c *FrameClock) Time() float64 {
+	return c.last.Sub(c.start).Seconds()
+}
+
+// FPS is the smoothed frame rate.
+func (c *FrameClock) FPS() float64 {
+	return c.fps
+}
+
+func (c *FrameClock) FrameCount() uint64 {
+	return c.frameCount
+}
+
+// FixedTimestep is the interval of the fixed update in seconds.
+func (c *FrameClock) FixedTimestep() float64 {
+	return c.step.Seconds()
+}
+
+// Alpha is how far between the last and the next fixed update the current
+// frame is, in the range 0..1. Renderers use it to interpolate simulation
+// state.
+func (c *FrameClock) Alpha() float64 {
+	if c.step <= 0 {
+		return 0
+	}
+	return float64(c.accumulator) / float64(c.step)
+}
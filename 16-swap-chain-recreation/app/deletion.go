@@ -0,0 +1,28 @@
+package app
+
+// deletionQueue collects the destroy call of every object as it is created
+// and runs them in reverse order on flush, so a partially initialized app
+// tears down exactly what it built.
+//
+// The app keeps one queue per lifetime: deletionQueue for objects that live
+// as long as the app, renderPassDeletionQueue for objects rebuilt when the
+// swapchain format changes, pipelineDeletionQueue for objects rebuilt with
+// them or when shaders are reloaded, and swapChainDeletionQueue for objects
+// rebuilt on every swapchain recreation.
+type deletionQueue struct {
+	deletors []func()
+}
+
+// push registers fn to be run on the next flush.
+func (q *deletionQueue) push(fn func()) {
+	q.deletors = append(q.deletors, fn)
+}
+
+// flush runs the registered functions, most recent first, and empties the
+// queue.
+func (q *deletionQueue) flush() {
+	for i := len(q.deletors) - 1; i >= 0; i-- {
+		q.deletors[i]()
+	}
+	q.deletors = nil
+}
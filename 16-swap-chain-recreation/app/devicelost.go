@@ -0,0 +1,73 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// checkDeviceLost logs a diagnostic dump if err is a lost device.
+func (a *app) checkDeviceLost(err error) {
+	if errors.Is(err, ErrDeviceLost) {
+		a.dumpDeviceLost(err)
+	}
+}
+
+// dumpDeviceLost logs what is known about the device and the frame loop when
+// the device is lost. VK_EXT_device_fault and the vendor checkpoint
+// extensions that would say what the GPU was doing aren't in the bindings, so
+// this is the state the CPU side had. Run the app with validation layers
+// enabled to find the offending call.
+func (a *app) dumpDeviceLost(err error) {
+	log.Printf("device lost: %s", err)
+
+	if unsafe.Pointer(a.physicalDevice) != vk.NullHandle {
+		properties := getDeviceProperties(a.physicalDevice)
+		log.Printf("  gpu: %s, vendor 0x%04x, device 0x%04x, driver 0x%08x, Vulkan %s",
+			vk.ToString(properties.DeviceName[:]), properties.VendorID, properties.DeviceID,
+			properties.DriverVersion, versionString(properties.ApiVersion))
+	}
+	log.Printf("  api version: %s", versionString(a.apiVersion))
+	log.Printf("  device extensions: %s", strings.Join(a.deviceExtensions, ", "))
+	log.Printf("  enabled features: %s", strings.Join(append(featureNames(&a.enabledFeatures), featureNames(&a.enabledFeatures2)...), ", "))
+	log.Printf("  swapchain: %dx%d, format %d, %d images",
+		a.swapChainExtent.Width, a.swapChainExtent.Height, a.swapChainImageFormat, len(a.swapChainImages))
+
+	if a.clock != nil {
+		log.Printf("  frame: %d, slot %d", a.clock.FrameCount(), a.currentFrame)
+	}
+
+	if a.timeline == nil {
+		return
+	}
+
+	log.Printf("  timeline: submitted %d, completed %d", a.timeline.submitted, a.timeline.completed)
+	if a.timeline.semaphore != vk.NullSemaphore {
+		value, res := a.timeline.funcs.SemaphoreCounterValue(a.timeline.semaphore)
+		if res != vk.Success {
+			log.Printf("  semaphore: %s", vk.Error(res))
+		} else {
+			log.Printf("  semaphore: value %d", value)
+		}
+	}
+	for i, fence := range a.timeline.fences {
+		if fence == vk.NullFence {
+			continue
+		}
+		log.Printf("  slot %d: value %d, fence %s", i, a.timeline.values[i], fenceStatus(vk.GetFenceStatus(a.logicalDevice, fence)))
+	}
+}
+
+func fenceStatus(res vk.Result) string {
+	switch res {
+	case vk.Success:
+		return "signaled"
+	case vk.NotReady:
+		return "unsignaled"
+	default:
+		return vk.Error(res).Error()
+	}
+}
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"vulkan-tutorial-go/16-swap-chain-recreation/spirv"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Stage identifies the part of initialization or the frame loop an Error
+// came from.
+type Stage int
+
+const (
+	StageUnknown Stage = iota
+	StageWindow
+	StageLoader
+	StageInstance
+	StageDebugMessenger
+	StageSurface
+	StageDeviceSelection
+	StageDevice
+	StageSwapChain
+	StageImageViews
+	StageRenderPass
+	StagePipeline
+	StageFramebuffers
+	StageCommandPool
+	StageCommandBuffers
+	StageSyncObjects
+	StageFrame
+)
+
+var stageNames = map[Stage]string{
+	StageUnknown:         "unknown",
+	StageWindow:          "window",
+	StageLoader:          "loader",
+	StageInstance:        "instance",
+	StageDebugMessenger:  "debug messenger",
+	StageSurface:         "surface",
+	StageDeviceSelection: "device selection",
+	StageDevice:          "logical device",
+	StageSwapChain:       "swapchain",
+	StageImageViews:      "image views",
+	StageRenderPass:      "render pass",
+	StagePipeline:        "pipeline",
+	StageFramebuffers:    "framebuffers",
+	StageCommandPool:     "command pool",
+	StageCommandBuffers:  "command buffers",
+	StageSyncObjects:     "sync objects",
+	StageFrame:           "frame",
+}
+
+func (s Stage) String() string {
+	if name, ok := stageNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Stage(%d)", int(s))
+}
+
+// Sentinel errors wrapped by Error, test for them with errors.Is.
+var (
+	ErrNoVulkanDevice            = errors.New("no gpus with vulkan support")
+	ErrNoSuitableDevice          = errors.New("no suitable gpu")
+	ErrMissingLayers             = errors.New("missing required layers")
+	ErrMissingInstanceExtensions = errors.New("missing required instance extensions")
+	ErrMissingDeviceExtensions   = errors.New("missing required device extensions")
+	ErrMissingFeatures           = errors.New("missing required device features")
+	ErrUnsupportedAPIVersion     = errors.New("unsupported Vulkan version")
+	ErrNoSurfaceSupport          = errors.New("no surface formats or present modes for the window surface")
+	ErrNoQueueFamilies           = errors.New("no graphics and present queue families")
+	ErrShaderNotFound            = errors.New("shader not found")
+	// ErrInvalidShader is spirv.ErrInvalidModule, it also matches the
+	// errors of SPIR-V reflection.
+	ErrInvalidShader = spirv.ErrInvalidModule
+	// ErrDeviceLost matches any Error whose Result is vk.ErrorDeviceLost, or
+	// that wraps vk.Error(vk.ErrorDeviceLost). The logical device is
+	// unusable afterwards, it has to be recreated.
+	ErrDeviceLost = errors.New("device lost")
+)
+
+// Error is returned by Run for every failure. Use errors.As to get at the
+// stage and vk.Result, and errors.Is with the sentinel errors above to tell
+// causes apart.
+type Error struct {
+	Stage Stage
+	// Result is the vk.Result of the failed call, vk.Success when the
+	// failure didn't come from Vulkan.
+	Result vk.Result
+	// Device is the name of the physical device the error is about, if any.
+	Device string
+	// Missing lists the layers, extensions or features that are missing.
+	Missing []string
+	Err     error
+	// Causes holds further errors, e.g. why each physical device was
+	// rejected during device selection.
+	Causes []error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	if e.Stage != StageUnknown {
+		b.WriteString(e.Stage.String())
+		b.WriteString(": ")
+	}
+	if e.Device != "" {
+		b.WriteString(e.Device)
+		b.WriteString(": ")
+	}
+
+	switch {
+	case e.Err != nil:
+		b.WriteString(e.Err.Error())
+	case e.Result != vk.Success:
+		b.WriteString(vk.Error(e.Result).Error())
+	default:
+		b.WriteString("failed")
+	}
+
+	if len(e.Missing) > 0 {
+		b.WriteString(" ")
+		b.WriteString(strings.Join(e.Missing, ", "))
+	}
+
+	if len(e.Causes) > 0 {
+		causes := make([]string, len(e.Causes))
+		for i, c := range e.Causes {
+			causes[i] = c.Error()
+		}
+		b.WriteString(" - ")
+		b.WriteString(strings.Join(causes, "; "))
+	}
+
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrDeviceLost and the device was lost, or
+// matches any of the causes. Err is handled by Unwrap.
+func (e *Error) Is(target error) bool {
+	if target == ErrDeviceLost && (e.Result == vk.ErrorDeviceLost || errors.Is(e.Err, vk.Error(vk.ErrorDeviceLost))) {
+		return true
+	}
+	for _, c := range e.Causes {
+		if errors.Is(c, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// vkError converts a failed vk.Result into an *Error. It returns nil for
+// vk.Success.
+func vkError(res vk.Result) error {
+	if res == vk.Success {
+		return nil
+	}
+	return &Error{Result: res, Err: vk.Error(res)}
+}
+
+// vkErrorf is vkError with context. It returns nil for vk.Success.
+func vkErrorf(res vk.Result, format string, args ...interface{}) error {
+	if res == vk.Success {
+		return nil
+	}
+	return &Error{Result: res, Err: fmt.Errorf(format+": %w", append(args, vk.Error(res))...)}
+}
+
+// withStage attributes err to stage, unless it already carries a stage.
+func withStage(stage Stage, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		if e.Stage == StageUnknown {
+			e.Stage = stage
+		}
+		return err
+	}
+
+	return &Error{Stage: stage, Err: err}
+}
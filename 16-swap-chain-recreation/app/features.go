@@ -0,0 +1,133 @@
+package app
+
+import (
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+	"vulkan-tutorial-go/16-swap-chain-recreation/vkext"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+var bool32Type = reflect.TypeOf(vk.Bool32(0))
+
+// featureName turns a feature field name into the name used by the Vulkan
+// spec, e.g. SamplerAnisotropy -> samplerAnisotropy.
+func featureName(field string) string {
+	r, size := utf8.DecodeRuneInString(field)
+	return string(unicode.ToLower(r)) + field[size:]
+}
+
+// eachFeature calls fn with the spec name and value of every feature in f, a
+// pointer to vk.PhysicalDeviceFeatures, vkext.Features or another struct of
+// vk.Bool32 fields. The feature names of the structures don't overlap.
+func eachFeature(f interface{}, fn func(name string, value *vk.Bool32)) {
+	v := reflect.ValueOf(f).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		switch {
+		case field.PkgPath != "":
+		case field.Type == bool32Type:
+			fn(featureName(field.Name), v.Field(i).Addr().Interface().(*vk.Bool32))
+		case field.Type.Kind() == reflect.Struct:
+			eachFeature(v.Field(i).Addr().Interface(), fn)
+		}
+	}
+}
+
+// missingFeatures returns the names of the features enabled in required that
+// are not in supported, both pointers to the same feature structure.
+func missingFeatures(supported, required interface{}) []string {
+	supportedSet := make(map[string]bool)
+	for _, name := range featureNames(supported) {
+		supportedSet[name] = true
+	}
+
+	var missing []string
+	for _, name := range featureNames(required) {
+		if !supportedSet[name] {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing
+}
+
+// enableFeatures sets every required feature in enabled, plus the optional
+// features the device supports. All are pointers to the same feature
+// structure.
+func enableFeatures(enabled, supported, required, optional interface{}) {
+	supportedSet := make(map[string]bool)
+	for _, name := range featureNames(supported) {
+		supportedSet[name] = true
+	}
+
+	enable := make(map[string]bool)
+	for _, name := range featureNames(required) {
+		enable[name] = true
+	}
+	for _, name := range featureNames(optional) {
+		if supportedSet[name] {
+			enable[name] = true
+		}
+	}
+
+	eachFeature(enabled, func(name string, value *vk.Bool32) {
+		if enable[name] {
+			*value = vk.True
+		}
+	})
+}
+
+// featureNames returns the names of the features enabled in f, a pointer to
+// a feature structure.
+func featureNames(f interface{}) []string {
+	var names []string
+	eachFeature(f, func(name string, value *vk.Bool32) {
+		if *value == vk.True {
+			names = append(names, name)
+		}
+	})
+
+	return names
+}
+
+// getDeviceFeatures returns the features of device, which supports
+// extensions.
+func (a *app) getDeviceFeatures(device vk.PhysicalDevice, extensions map[string]bool) (vk.PhysicalDeviceFeatures, vkext.Features) {
+	features2 := a.instanceFuncs.PhysicalDeviceFeatures(device, a.deviceAPIVersion(device), extensions)
+	return getCoreFeatures(device), features2
+}
+
+// deviceAPIVersion is the Vulkan version usable with device, the lower of its
+// own and the instance's.
+func (a *app) deviceAPIVersion(device vk.PhysicalDevice) uint32 {
+	version := getDeviceProperties(device).ApiVersion
+	if a.instanceAPIVersion < version {
+		version = a.instanceAPIVersion
+	}
+	return version
+}
+
+func getCoreFeatures(device vk.PhysicalDevice) vk.PhysicalDeviceFeatures {
+	var features vk.PhysicalDeviceFeatures
+	vk.GetPhysicalDeviceFeatures(device, &features)
+	features.Deref()
+	features.Free()
+
+	return features
+}
+
+func getDeviceProperties(device vk.PhysicalDevice) vk.PhysicalDeviceProperties {
+	var properties vk.PhysicalDeviceProperties
+	vk.GetPhysicalDeviceProperties(device, &properties)
+	properties.Deref()
+	properties.Free()
+
+	return properties
+}
+
+func versionString(version uint32) string {
+	return vk.Version(version).String()
+}
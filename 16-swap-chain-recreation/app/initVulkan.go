@@ -1,11 +1,13 @@
 package app
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
-	"runtime"
+	"log"
 	"unsafe"
+	"vulkan-tutorial-go/16-swap-chain-recreation/memory"
+	"vulkan-tutorial-go/16-swap-chain-recreation/spirv"
+	"vulkan-tutorial-go/16-swap-chain-recreation/vkext"
 
 	"github.com/go-gl/glfw/v3.3/glfw"
 	vk "github.com/vulkan-go/vulkan"
@@ -15,82 +17,93 @@ func (a *app) initVulkan() error {
 
 	procAddr := glfw.GetVulkanGetInstanceProcAddress()
 	if procAddr == nil {
-		return fmt.Errorf("GetInstanceProcAddress is nil")
+		return &Error{Stage: StageLoader, Err: fmt.Errorf("GetInstanceProcAddress is nil")}
 	}
 	vk.SetGetInstanceProcAddr(procAddr)
+	vkext.SetGetInstanceProcAddr(procAddr)
 
 	err := vk.Init()
 	if err != nil {
-		return err
+		return withStage(StageLoader, err)
 	}
 
 	err = a.createInstance()
 	if err != nil {
-		return err
+		return withStage(StageInstance, err)
 	}
 
 	if a.config.EnableValidationLayers {
 		err = a.setupDebugMessenger()
 		if err != nil {
-			return err
+			return withStage(StageDebugMessenger, err)
 		}
 	}
 
 	err = a.createWindowSurface()
 	if err != nil {
-		return err
+		return withStage(StageSurface, err)
 	}
 
 	err = a.pickPhysicalDevice()
 	if err != nil {
-		return err
+		return withStage(StageDeviceSelection, err)
 	}
 
 	err = a.createLogicalDevice()
 	if err != nil {
-		return err
+		return withStage(StageDevice, err)
 	}
 
+	a.createAllocator()
+	a.logMemoryBudgetSource()
+
 	err = a.createSwapChain()
 	if err != nil {
-		return err
+		return withStage(StageSwapChain, err)
 	}
 
 	err = a.createImageViews()
 	if err != nil {
-		return err
+		return withStage(StageImageViews, err)
 	}
 
 	err = a.createRenderPass()
 	if err != nil {
-		return err
+		return withStage(StageRenderPass, err)
 	}
 
 	err = a.createGraphicsPipeline()
 	if err != nil {
-		return err
+		return withStage(StagePipeline, err)
 	}
 
 	err = a.createFrameBuffers()
 	if err != nil {
-		return err
+		return withStage(StageFramebuffers, err)
 	}
 
 	err = a.createCommandPool()
 	if err != nil {
-		return err
+		return withStage(StageCommandPool, err)
 	}
 
 	err = a.createCommandBuffers()
 	if err != nil {
-		return err
+		return withStage(StageCommandBuffers, err)
+	}
+
+	err = a.createRecordWorkers()
+	if err != nil {
+		return withStage(StageCommandBuffers, err)
 	}
 
 	err = a.createSyncObjects()
 	if err != nil {
-		return err
+		return withStage(StageSyncObjects, err)
 	}
 
+	a.updateCameraAspect()
+
 	return nil
 }
 
@@ -102,126 +115,275 @@ func (a *app) createSyncObjects() error {
 		Flags: 0,
 	}
 
-	fenceInfo := vk.FenceCreateInfo{
-		SType: vk.StructureTypeFenceCreateInfo,
-		PNext: nil,
-		Flags: vk.FenceCreateFlags(vk.FenceCreateSignaledBit),
+	var timelineFuncs *vkext.Device
+	if a.enabledFeatures2.Vulkan12.TimelineSemaphore == vk.True && a.deviceFuncs.TimelineSemaphores() {
+		timelineFuncs = a.deviceFuncs
 	}
 
+	timeline, err := newGPUTimeline(a.logicalDevice, timelineFuncs, maxFramesInFlight, a.leaks)
+	if err != nil {
+		return err
+	}
+
+	a.timeline = timeline
+	a.deletionQueue.push(timeline.destroy)
 	a.imageAvailableSemaphores = make([]vk.Semaphore, maxFramesInFlight)
 	a.renderFinishedSemaphores = make([]vk.Semaphore, maxFramesInFlight)
-	a.inFlightFences = make([]vk.Fence, maxFramesInFlight)
-	a.imagesInFlight = make([]vk.Fence, len(a.swapChainImages))
-	for i := range a.imagesInFlight {
-		a.imagesInFlight[i] = vk.NullFence
-	}
+	a.imagesInFlight = make([]uint64, len(a.swapChainImages))
 	for i := 0; i < maxFramesInFlight; i++ {
 		var imageAvailableSemaphore vk.Semaphore
-		err := vk.Error(vk.CreateSemaphore(a.logicalDevice, &semaphoreInfo, nil, &imageAvailableSemaphore))
+		err = vkError(vk.CreateSemaphore(a.logicalDevice, &semaphoreInfo, nil, &imageAvailableSemaphore))
 		if err != nil {
 			return err
 		}
 
 		a.imageAvailableSemaphores[i] = imageAvailableSemaphore
+		a.deletionQueue.push(a.leaks.track("semaphore", imageAvailableSemaphore, func() {
+			vk.DestroySemaphore(a.logicalDevice, imageAvailableSemaphore, nil)
+		}))
 
 		var renderFinishedSemaphore vk.Semaphore
-		err = vk.Error(vk.CreateSemaphore(a.logicalDevice, &semaphoreInfo, nil, &renderFinishedSemaphore))
+		err = vkError(vk.CreateSemaphore(a.logicalDevice, &semaphoreInfo, nil, &renderFinishedSemaphore))
 		if err != nil {
 			return err
 		}
 
 		a.renderFinishedSemaphores[i] = renderFinishedSemaphore
+		a.deletionQueue.push(a.leaks.track("semaphore", renderFinishedSemaphore, func() {
+			vk.DestroySemaphore(a.logicalDevice, renderFinishedSemaphore, nil)
+		}))
+	}
 
-		var inFlightFence vk.Fence
-		err = vk.Error(vk.CreateFence(a.logicalDevice, &fenceInfo, nil, &inFlightFence))
+	return nil
+}
+
+// createCommandBuffers allocates one primary command buffer from each frame's
+// command pool. They are reset and re-recorded every frame by drawFrame.
+func (a *app) createCommandBuffers() error {
+	a.commandBuffers = make([]vk.CommandBuffer, maxFramesInFlight)
+
+	for i := range a.commandBuffers {
+		commandBufferCreateInfo := vk.CommandBufferAllocateInfo{
+			SType:              vk.StructureTypeCommandBufferAllocateInfo,
+			PNext:              nil,
+			CommandPool:        a.commandPools[i],
+			Level:              vk.CommandBufferLevelPrimary,
+			CommandBufferCount: 1,
+		}
+
+		commandBuffers := make([]vk.CommandBuffer, 1)
+		err := vkError(vk.AllocateCommandBuffers(a.logicalDevice, &commandBufferCreateInfo, commandBuffers))
 		if err != nil {
 			return err
 		}
 
-		a.inFlightFences[i] = inFlightFence
+		a.commandBuffers[i] = commandBuffers[0]
 	}
 
 	return nil
 }
 
-func (a *app) createCommandBuffers() error {
-	commandBuffers := make([]vk.CommandBuffer, len(a.swapChainFrameBuffers))
-
-	commandBufferCreateInfo := vk.CommandBufferAllocateInfo{
-		SType:              vk.StructureTypeCommandBufferAllocateInfo,
-		PNext:              nil,
-		CommandPool:        a.commandPool,
-		Level:              vk.CommandBufferLevelPrimary,
-		CommandBufferCount: uint32(len(commandBuffers)),
+// recordCommandBuffer records the frame's commands into commandBuffer, which
+// must have been reset. The user supplied record callback runs inside the
+// render pass, or dynamic rendering, with the graphics pipeline bound.
+func (a *app) recordCommandBuffer(commandBuffer vk.CommandBuffer, imageIndex uint32) error {
+	cbBeginInfo := vk.CommandBufferBeginInfo{
+		SType: vk.StructureTypeCommandBufferBeginInfo,
+		Flags: vk.CommandBufferUsageFlags(vk.CommandBufferUsageOneTimeSubmitBit),
 	}
 
-	err := vk.Error(vk.AllocateCommandBuffers(a.logicalDevice, &commandBufferCreateInfo, commandBuffers))
+	err := vkError(vk.BeginCommandBuffer(commandBuffer, &cbBeginInfo))
 	if err != nil {
 		return err
 	}
 
-	a.commandBuffers = commandBuffers
-
-	for i := range a.commandBuffers {
-		cbBeginInfo := vk.CommandBufferBeginInfo{
-			SType: vk.StructureTypeCommandBufferBeginInfo,
-		}
+	framebuffer := vk.NullFramebuffer
+	if !a.dynamicRendering {
+		framebuffer = a.swapChainFrameBuffers[imageIndex]
+	}
 
-		err := vk.Error(vk.BeginCommandBuffer(a.commandBuffers[i], &cbBeginInfo))
+	if len(a.recordWorkers) > 0 {
+		secondaries, err := a.recordSecondaries(a.currentFrame, framebuffer)
 		if err != nil {
 			return err
 		}
 
-		var clearColor vk.ClearValue
-		clearColor.SetColor([]float32{0, 0, 0, 1})
+		a.beginRendering(commandBuffer, imageIndex, true)
+		vk.CmdExecuteCommands(commandBuffer, uint32(len(secondaries)), secondaries)
+		a.endRendering(commandBuffer, imageIndex)
+
+		return vkError(vk.EndCommandBuffer(commandBuffer))
+	}
+
+	a.beginRendering(commandBuffer, imageIndex, false)
+	vk.CmdBindPipeline(commandBuffer, vk.PipelineBindPointGraphics, a.graphicsPipeline)
+	a.setViewportAndScissor(commandBuffer)
+
+	if a.config.Record != nil {
+		err = a.config.Record(commandBuffer, framebuffer, a.swapChainExtent)
+	} else {
+		vk.CmdDraw(commandBuffer, 3, 1, 0, 0)
+	}
+
+	a.endRendering(commandBuffer, imageIndex)
+	if err != nil {
+		return err
+	}
+
+	return vkError(vk.EndCommandBuffer(commandBuffer))
+}
+
+// beginRendering starts rendering to the swapchain image imageIndex, cleared
+// to black. With secondary set the commands come from secondary command
+// buffers.
+func (a *app) beginRendering(commandBuffer vk.CommandBuffer, imageIndex uint32, secondary bool) {
+	var clearColor vk.ClearValue
+	clearColor.SetColor([]float32{0, 0, 0, 1})
+	renderArea := vk.Rect2D{
+		Offset: vk.Offset2D{
+			X: 0, Y: 0,
+		},
+		Extent: a.swapChainExtent,
+	}
+
+	if !a.dynamicRendering {
 		renderPassInfo := vk.RenderPassBeginInfo{
-			SType:       vk.StructureTypeRenderPassBeginInfo,
-			RenderPass:  a.renderPass,
-			Framebuffer: a.swapChainFrameBuffers[i],
-			RenderArea: vk.Rect2D{
-				Offset: vk.Offset2D{
-					X: 0, Y: 0,
-				},
-				Extent: a.swapChainExtent,
-			},
+			SType:           vk.StructureTypeRenderPassBeginInfo,
+			RenderPass:      a.renderPass,
+			Framebuffer:     a.swapChainFrameBuffers[imageIndex],
+			RenderArea:      renderArea,
 			ClearValueCount: 1,
 			PClearValues:    []vk.ClearValue{clearColor},
 		}
-		vk.CmdBeginRenderPass(a.commandBuffers[i], &renderPassInfo, vk.SubpassContentsInline)
-		vk.CmdBindPipeline(a.commandBuffers[i], vk.PipelineBindPointGraphics, a.graphicsPipeline)
-		vk.CmdDraw(a.commandBuffers[i], 3, 1, 0, 0)
-		vk.CmdEndRenderPass(a.commandBuffers[i])
-		err = vk.Error(vk.EndCommandBuffer(a.commandBuffers[i]))
-		if err != nil {
-			return err
+
+		contents := vk.SubpassContentsInline
+		if secondary {
+			contents = vk.SubpassContentsSecondaryCommandBuffers
 		}
-	}
+		vk.CmdBeginRenderPass(commandBuffer, &renderPassInfo, contents)
+		return
+	}
+
+	// The render pass's initial layout and subpass dependency: the image
+	// is written once the acquire semaphore, waited on at the color
+	// attachment output stage, has signalled.
+	a.deviceFuncs.CmdPipelineBarrier2(commandBuffer, 0, []vkext.ImageMemoryBarrier2{{
+		SrcStageMask:        vkext.PipelineStage2ColorAttachmentOutput,
+		SrcAccessMask:       vkext.Access2None,
+		DstStageMask:        vkext.PipelineStage2ColorAttachmentOutput,
+		DstAccessMask:       vkext.Access2ColorAttachmentWrite,
+		OldLayout:           vk.ImageLayoutUndefined,
+		NewLayout:           vk.ImageLayoutColorAttachmentOptimal,
+		SrcQueueFamilyIndex: vk.QueueFamilyIgnored,
+		DstQueueFamilyIndex: vk.QueueFamilyIgnored,
+		Image:               a.swapChainImages[imageIndex],
+		SubresourceRange:    colorSubresourceRange,
+	}})
+
+	var flags vkext.RenderingFlags
+	if secondary {
+		flags = vkext.RenderingContentsSecondaryCommandBuffers
+	}
+	a.deviceFuncs.CmdBeginRendering(commandBuffer, &vkext.RenderingInfo{
+		Flags:      flags,
+		RenderArea: renderArea,
+		LayerCount: 1,
+		ColorAttachments: []vkext.RenderingAttachment{{
+			ImageView:   a.swapChainImageViews[imageIndex],
+			ImageLayout: vk.ImageLayoutColorAttachmentOptimal,
+			LoadOp:      vk.AttachmentLoadOpClear,
+			StoreOp:     vk.AttachmentStoreOpStore,
+			ClearValue:  clearColor,
+		}},
+	})
+}
 
-	return nil
+// endRendering ends what beginRendering started and leaves the swapchain
+// image imageIndex ready to present.
+func (a *app) endRendering(commandBuffer vk.CommandBuffer, imageIndex uint32) {
+	if !a.dynamicRendering {
+		vk.CmdEndRenderPass(commandBuffer)
+		return
+	}
+
+	a.deviceFuncs.CmdEndRendering(commandBuffer)
+
+	// The render pass's final layout. Presenting waits on the render
+	// finished semaphore, which orders it after the writes.
+	a.deviceFuncs.CmdPipelineBarrier2(commandBuffer, 0, []vkext.ImageMemoryBarrier2{{
+		SrcStageMask:        vkext.PipelineStage2ColorAttachmentOutput,
+		SrcAccessMask:       vkext.Access2ColorAttachmentWrite,
+		DstStageMask:        vkext.PipelineStage2None,
+		DstAccessMask:       vkext.Access2None,
+		OldLayout:           vk.ImageLayoutColorAttachmentOptimal,
+		NewLayout:           vk.ImageLayoutPresentSrc,
+		SrcQueueFamilyIndex: vk.QueueFamilyIgnored,
+		DstQueueFamilyIndex: vk.QueueFamilyIgnored,
+		Image:               a.swapChainImages[imageIndex],
+		SubresourceRange:    colorSubresourceRange,
+	}})
+}
+
+// setViewportAndScissor sets the dynamic viewport and scissor to cover the
+// swap chain, so the pipeline doesn't have to be rebuilt when it's resized.
+func (a *app) setViewportAndScissor(commandBuffer vk.CommandBuffer) {
+	viewports := []vk.Viewport{{
+		X:        0,
+		Y:        0,
+		Width:    float32(a.swapChainExtent.Width),
+		Height:   float32(a.swapChainExtent.Height),
+		MinDepth: 0,
+		MaxDepth: 1,
+	}}
+
+	scissors := []vk.Rect2D{{
+		Offset: vk.Offset2D{
+			X: 0,
+			Y: 0,
+		},
+		Extent: a.swapChainExtent,
+	}}
+
+	vk.CmdSetViewport(commandBuffer, 0, uint32(len(viewports)), viewports)
+	vk.CmdSetScissor(commandBuffer, 0, uint32(len(scissors)), scissors)
 }
 
+// createCommandPool creates one command pool per frame in flight so a frame's
+// pool can be reset as a whole once its fence has signalled.
 func (a *app) createCommandPool() error {
-	indices := findQueueFamilies(a.physicalDevice, a.windowSurface)
+	indices, err := findQueueFamilies(a.physicalDevice, a.windowSurface)
+	if err != nil {
+		return err
+	}
 
 	commandPoolCreateInfo := vk.CommandPoolCreateInfo{
 		SType:            vk.StructureTypeCommandPoolCreateInfo,
 		PNext:            nil,
-		Flags:            0,
+		Flags:            vk.CommandPoolCreateFlags(vk.CommandPoolCreateTransientBit),
 		QueueFamilyIndex: *indices.graphicsFamily,
 	}
 
-	var commandPool vk.CommandPool
-	err := vk.Error(vk.CreateCommandPool(a.logicalDevice, &commandPoolCreateInfo, nil, &commandPool))
-	if err != nil {
-		return err
-	}
+	a.commandPools = make([]vk.CommandPool, maxFramesInFlight)
+	for i := range a.commandPools {
+		var commandPool vk.CommandPool
+		err = vkError(vk.CreateCommandPool(a.logicalDevice, &commandPoolCreateInfo, nil, &commandPool))
+		if err != nil {
+			return err
+		}
 
-	a.commandPool = commandPool
+		a.commandPools[i] = commandPool
+		a.deletionQueue.push(a.leaks.track("command pool", commandPool, func() {
+			vk.DestroyCommandPool(a.logicalDevice, commandPool, nil)
+		}))
+	}
 
 	return nil
 }
 
 func (a *app) createFrameBuffers() error {
+	if a.dynamicRendering {
+		return nil
+	}
 
 	a.swapChainFrameBuffers = make([]vk.Framebuffer, len(a.swapChainImageViews))
 
@@ -243,18 +405,26 @@ func (a *app) createFrameBuffers() error {
 		}
 
 		var fb vk.Framebuffer
-		err := vk.Error(vk.CreateFramebuffer(a.logicalDevice, &fbCreateInfo, nil, &fb))
+		err := vkError(vk.CreateFramebuffer(a.logicalDevice, &fbCreateInfo, nil, &fb))
 		if err != nil {
 			return err
 		}
 
 		a.swapChainFrameBuffers[i] = fb
+		a.swapChainDeletionQueue.push(a.leaks.track("framebuffer", fb, func() {
+			vk.DestroyFramebuffer(a.logicalDevice, fb, nil)
+		}))
 	}
 
 	return nil
 }
 
+// createRenderPass creates the render pass, unless dynamic rendering is used
+// and the pipeline and frame do without one.
 func (a *app) createRenderPass() error {
+	if a.dynamicRendering {
+		return nil
+	}
 
 	colorAttachments := []vk.AttachmentDescription{{
 		Flags:          0,
@@ -300,65 +470,110 @@ func (a *app) createRenderPass() error {
 	}
 
 	var renderPass vk.RenderPass
-	err := vk.Error(vk.CreateRenderPass(a.logicalDevice, &renderPassCreateInfo, nil, &renderPass))
+	err := vkError(vk.CreateRenderPass(a.logicalDevice, &renderPassCreateInfo, nil, &renderPass))
 	if err != nil {
 		return err
 	}
 
 	a.renderPass = renderPass
+	a.renderPassDeletionQueue.push(a.leaks.track("render pass", renderPass, func() {
+		vk.DestroyRenderPass(a.logicalDevice, renderPass, nil)
+	}))
 
 	return nil
 }
 
 func (a *app) createGraphicsPipeline() error {
 
-	_, fileName, _, _ := runtime.Caller(1)
-	fileName, err := filepath.Abs(fileName)
+	fragCode, err := a.loadShader(a.config.FragmentShader.Module)
 	if err != nil {
 		return err
 	}
 
-	fragCode, err := ioutil.ReadFile(filepath.Join(filepath.Dir(fileName), "../shaders/frag.spv"))
+	vertCode, err := a.loadShader(a.config.VertexShader.Module)
 	if err != nil {
 		return err
 	}
 
-	vertCode, err := ioutil.ReadFile(filepath.Join(filepath.Dir(fileName), "../shaders/vert.spv"))
+	return a.buildGraphicsPipeline(vertCode, fragCode)
+}
+
+// buildGraphicsPipeline creates the pipeline layout and graphics pipeline
+// from the given SPIR-V and registers them on pipelineDeletionQueue.
+func (a *app) buildGraphicsPipeline(vertCode, fragCode []uint32) error {
+	vertStage, fragStage := a.config.VertexShader, a.config.FragmentShader
+	vertSPIRV, vertEntry, err := reflectEntryPoint(vertCode, vertStage.EntryPoint, vk.ShaderStageVertexBit)
 	if err != nil {
-		return err
+		return fmt.Errorf("vertex shader %s: %w", vertStage.Module, err)
+	}
+	fragSPIRV, fragEntry, err := reflectEntryPoint(fragCode, fragStage.EntryPoint, vk.ShaderStageFragmentBit)
+	if err != nil {
+		return fmt.Errorf("fragment shader %s: %w", fragStage.Module, err)
 	}
 
-	buf1 := make([]byte, 0, len(fragCode))
-	fragCode = append(buf1, fragCode...)
-	buf2 := make([]byte, 0, len(vertCode))
-	vertCode = append(buf2, vertCode...)
+	// The data of the specialization infos is C memory the driver reads
+	// while creating the pipeline.
+	vertSpecialization, vertSpecializationData, err := specializationInfo(vertStage.Specialization, vertSPIRV)
+	if err != nil {
+		return fmt.Errorf("vertex shader %s: %w", vertStage.Module, err)
+	}
+	defer vertSpecializationData.Free()
+	fragSpecialization, fragSpecializationData, err := specializationInfo(fragStage.Specialization, fragSPIRV)
+	if err != nil {
+		return fmt.Errorf("fragment shader %s: %w", fragStage.Module, err)
+	}
+	defer fragSpecializationData.Free()
+
+	// The triangle is generated in the vertex shader, there are no vertex
+	// buffers yet.
+	var vertexAttributes []vk.VertexInputAttributeDescription
+	err = spirv.CheckVertexInput(vertEntry, vertexAttributes)
+	if err != nil {
+		return err
+	}
+	err = spirv.CheckInterface(vertEntry, fragEntry)
+	if err != nil {
+		return err
+	}
 
+	// The modules are only needed until the pipeline is created, whether or
+	// not that succeeds.
 	fragModule, err := a.createShaderModule(fragCode)
 	if err != nil {
 		return err
 	}
+	defer a.leaks.track("shader module", fragModule, func() {
+		vk.DestroyShaderModule(a.logicalDevice, fragModule, nil)
+	})()
 	vertModule, err := a.createShaderModule(vertCode)
 	if err != nil {
 		return err
 	}
+	defer a.leaks.track("shader module", vertModule, func() {
+		vk.DestroyShaderModule(a.logicalDevice, vertModule, nil)
+	})()
 
 	vertStageCreateInfo := vk.PipelineShaderStageCreateInfo{
-		SType:  vk.StructureTypePipelineShaderStageCreateInfo,
-		Stage:  vk.ShaderStageVertexBit,
-		Module: vertModule,
-		PName:  "main\x00",
+		SType:               vk.StructureTypePipelineShaderStageCreateInfo,
+		Stage:               vk.ShaderStageVertexBit,
+		Module:              vertModule,
+		PName:               nullTerminate(vertStage.EntryPoint),
+		PSpecializationInfo: vertSpecialization,
 	}
 	fragStageCreateInfo := vk.PipelineShaderStageCreateInfo{
-		SType:  vk.StructureTypePipelineShaderStageCreateInfo,
-		Stage:  vk.ShaderStageFragmentBit,
-		Module: fragModule,
-		PName:  "main\x00",
+		SType:               vk.StructureTypePipelineShaderStageCreateInfo,
+		Stage:               vk.ShaderStageFragmentBit,
+		Module:              fragModule,
+		PName:               nullTerminate(fragStage.EntryPoint),
+		PSpecializationInfo: fragSpecialization,
 	}
 
 	shaderStages := []vk.PipelineShaderStageCreateInfo{vertStageCreateInfo, fragStageCreateInfo}
 
 	vertexInputStateCreateInfo := vk.PipelineVertexInputStateCreateInfo{
-		SType: vk.StructureTypePipelineVertexInputStateCreateInfo,
+		SType:                           vk.StructureTypePipelineVertexInputStateCreateInfo,
+		VertexAttributeDescriptionCount: uint32(len(vertexAttributes)),
+		PVertexAttributeDescriptions:    vertexAttributes,
 	}
 
 	inputAssemblyStateCreateInfo := vk.PipelineInputAssemblyStateCreateInfo{
@@ -367,29 +582,19 @@ func (a *app) createGraphicsPipeline() error {
 		PrimitiveRestartEnable: vk.False,
 	}
 
-	viewports := []vk.Viewport{{
-		X:        0,
-		Y:        0,
-		Width:    float32(a.swapChainExtent.Width),
-		Height:   float32(a.swapChainExtent.Height),
-		MinDepth: 0,
-		MaxDepth: 1,
-	}}
-
-	scissors := []vk.Rect2D{{
-		Offset: vk.Offset2D{
-			X: 0,
-			Y: 0,
-		},
-		Extent: a.swapChainExtent,
-	}}
-
+	// Viewport and scissor are dynamic so the pipeline survives swapchain
+	// resizes, see setViewportAndScissor.
 	viewportStateCreateInfo := vk.PipelineViewportStateCreateInfo{
 		SType:         vk.StructureTypePipelineViewportStateCreateInfo,
-		ViewportCount: uint32(len(viewports)),
-		PViewports:    viewports,
-		ScissorCount:  uint32(len(scissors)),
-		PScissors:     scissors,
+		ViewportCount: 1,
+		ScissorCount:  1,
+	}
+
+	dynamicStates := []vk.DynamicState{vk.DynamicStateViewport, vk.DynamicStateScissor}
+	dynamicStateCreateInfo := vk.PipelineDynamicStateCreateInfo{
+		SType:             vk.StructureTypePipelineDynamicStateCreateInfo,
+		DynamicStateCount: uint32(len(dynamicStates)),
+		PDynamicStates:    dynamicStates,
 	}
 
 	rasterizer := vk.PipelineRasterizationStateCreateInfo{
@@ -442,21 +647,46 @@ func (a *app) createGraphicsPipeline() error {
 		BlendConstants:  [4]float32{0, 0, 0, 0},
 	}
 
+	// The layout follows the shaders, so it can't fall out of sync with
+	// them.
+	setLayouts, err := a.createDescriptorSetLayouts(vertEntry, fragEntry)
+	if err != nil {
+		return err
+	}
+	a.descriptorSetLayouts = setLayouts
+
+	pushConstantRanges := spirv.PushConstantRanges(vertEntry, fragEntry)
 	pipelineLayoutCreateInfo := vk.PipelineLayoutCreateInfo{
-		SType: vk.StructureTypePipelineLayoutCreateInfo,
+		SType:                  vk.StructureTypePipelineLayoutCreateInfo,
+		SetLayoutCount:         uint32(len(setLayouts)),
+		PSetLayouts:            setLayouts,
+		PushConstantRangeCount: uint32(len(pushConstantRanges)),
+		PPushConstantRanges:    pushConstantRanges,
 	}
 
 	var pipelineLayout vk.PipelineLayout
-	err = vk.Error(vk.CreatePipelineLayout(a.logicalDevice, &pipelineLayoutCreateInfo, nil, &pipelineLayout))
+	err = vkError(vk.CreatePipelineLayout(a.logicalDevice, &pipelineLayoutCreateInfo, nil, &pipelineLayout))
 	if err != nil {
 		return err
 	}
 
 	a.pipelineLayout = pipelineLayout
+	a.pipelineDeletionQueue.push(a.leaks.track("pipeline layout", pipelineLayout, func() {
+		vk.DestroyPipelineLayout(a.logicalDevice, pipelineLayout, nil)
+	}))
+
+	// With dynamic rendering the pipeline names the attachment formats
+	// instead of a render pass.
+	var renderingInfo unsafe.Pointer
+	if a.dynamicRendering {
+		pipelineRenderingInfo := vkext.NewPipelineRenderingCreateInfo([]vk.Format{a.swapChainImageFormat})
+		defer pipelineRenderingInfo.Free()
+		renderingInfo = pipelineRenderingInfo.Ref()
+	}
 
 	pipelineCreateInfo := []vk.GraphicsPipelineCreateInfo{{
 		SType:               vk.StructureTypeGraphicsPipelineCreateInfo,
-		PNext:               nil,
+		PNext:               renderingInfo,
 		Flags:               0,
 		StageCount:          uint32(len(shaderStages)),
 		PStages:             shaderStages,
@@ -468,7 +698,7 @@ func (a *app) createGraphicsPipeline() error {
 		PMultisampleState:   &multisamplingCreateInfo,
 		PDepthStencilState:  nil,
 		PColorBlendState:    &colorBlendingCreateInfo,
-		PDynamicState:       nil,
+		PDynamicState:       &dynamicStateCreateInfo,
 		Layout:              pipelineLayout,
 		RenderPass:          a.renderPass,
 		Subpass:             0,
@@ -477,46 +707,49 @@ func (a *app) createGraphicsPipeline() error {
 	}}
 
 	var graphicsPipelines = make([]vk.Pipeline, 1)
-	vk.CreateGraphicsPipelines(a.logicalDevice, vk.NullPipelineCache, uint32(len(pipelineCreateInfo)), pipelineCreateInfo, nil, graphicsPipelines)
-
-	if len(graphicsPipelines) == 0 {
-		return fmt.Errorf("could not create graphics pipeline")
-	} else {
-		a.graphicsPipeline = graphicsPipelines[0]
+	err = vkErrorf(vk.CreateGraphicsPipelines(a.logicalDevice, vk.NullPipelineCache, uint32(len(pipelineCreateInfo)), pipelineCreateInfo, nil, graphicsPipelines), "could not create graphics pipeline")
+	if err != nil {
+		return err
+	}
+	if graphicsPipelines[0] == vk.NullPipeline {
+		return fmt.Errorf("could not create graphics pipeline: driver returned a null pipeline")
 	}
 
-	vk.DestroyShaderModule(a.logicalDevice, fragModule, nil)
-	vk.DestroyShaderModule(a.logicalDevice, vertModule, nil)
+	graphicsPipeline := graphicsPipelines[0]
+	a.graphicsPipeline = graphicsPipeline
+	a.pipelineDeletionQueue.push(a.leaks.track("pipeline", graphicsPipeline, func() {
+		vk.DestroyPipeline(a.logicalDevice, graphicsPipeline, nil)
+	}))
+
 	return nil
 }
 
-func (a *app) createShaderModule(code []byte) (vk.ShaderModule, error) {
+func (a *app) createShaderModule(code []uint32) (vk.ShaderModule, error) {
 	createInfo := vk.ShaderModuleCreateInfo{
 		SType:    vk.StructureTypeShaderModuleCreateInfo,
 		PNext:    nil,
 		Flags:    0,
-		CodeSize: uint(len(code)),
-		PCode:    sliceUint32(code),
+		CodeSize: uint(len(code) * 4),
+		PCode:    code,
 	}
 
 	var shaderModule vk.ShaderModule
-	err := vk.Error(vk.CreateShaderModule(a.logicalDevice, &createInfo, nil, &shaderModule))
+	err := vkError(vk.CreateShaderModule(a.logicalDevice, &createInfo, nil, &shaderModule))
 	if err != nil {
-		return nil, fmt.Errorf("could not create shader module - " + err.Error())
+		return nil, withStage(StagePipeline, err)
 	}
 
 	return shaderModule, nil
 }
 
-func sliceUint32(data []byte) []uint32 {
-	const m = 0x7fffffff
-	return (*[m / 4]uint32)(unsafe.Pointer((*sliceHeader)(unsafe.Pointer(&data)).Data))[:len(data)/4]
-}
-
-type sliceHeader struct {
-	Data uintptr
-	Len  int
-	Cap  int
+// colorSubresourceRange is the single mip level and layer of a swapchain
+// image.
+var colorSubresourceRange = vk.ImageSubresourceRange{
+	AspectMask:     vk.ImageAspectFlags(vk.ImageAspectColorBit),
+	BaseMipLevel:   0,
+	LevelCount:     1,
+	BaseArrayLayer: 0,
+	LayerCount:     1,
 }
 
 func (a *app) createImageViews() error {
@@ -536,28 +769,28 @@ func (a *app) createImageViews() error {
 				B: vk.ComponentSwizzleIdentity,
 				A: vk.ComponentSwizzleIdentity,
 			},
-			SubresourceRange: vk.ImageSubresourceRange{
-				AspectMask:     vk.ImageAspectFlags(vk.ImageAspectColorBit),
-				BaseMipLevel:   0,
-				LevelCount:     1,
-				BaseArrayLayer: 0,
-				LayerCount:     1,
-			},
+			SubresourceRange: colorSubresourceRange,
 		}
 		var imageView vk.ImageView
-		err := vk.Error(vk.CreateImageView(a.logicalDevice, &createInfo, nil, &imageView))
+		err := vkError(vk.CreateImageView(a.logicalDevice, &createInfo, nil, &imageView))
 		if err != nil {
 			return err
 		}
 
 		a.swapChainImageViews[i] = imageView
+		a.swapChainDeletionQueue.push(a.leaks.track("image view", imageView, func() {
+			vk.DestroyImageView(a.logicalDevice, imageView, nil)
+		}))
 	}
 
 	return nil
 }
 
 func (a *app) createSwapChain() error {
-	swapChainSupport := querySwapChainSupport(a.physicalDevice, a.windowSurface)
+	swapChainSupport, err := querySwapChainSupport(a.physicalDevice, a.windowSurface)
+	if err != nil {
+		return err
+	}
 	swapChainSupport.capabilities.Deref()
 	swapChainSupport.capabilities.Free()
 
@@ -586,7 +819,16 @@ func (a *app) createSwapChain() error {
 		OldSwapchain:     vk.NullSwapchain,
 	}
 
-	indices := findQueueFamilies(a.physicalDevice, a.windowSurface)
+	if a.HasDeviceExtension(fullScreenExclusiveExtension) {
+		exclusiveInfo := vkext.NewSurfaceFullScreenExclusiveInfo(a.fullScreenExclusive())
+		defer exclusiveInfo.Free()
+		createInfo.PNext = exclusiveInfo.Ref()
+	}
+
+	indices, err := findQueueFamilies(a.physicalDevice, a.windowSurface)
+	if err != nil {
+		return err
+	}
 	queueFamilies := []uint32{*indices.presentFamily, *indices.graphicsFamily}
 
 	if *indices.graphicsFamily != *indices.presentFamily {
@@ -598,17 +840,32 @@ func (a *app) createSwapChain() error {
 	}
 
 	var swapChain vk.Swapchain
-	err := vk.Error(vk.CreateSwapchain(a.logicalDevice, &createInfo, nil, &swapChain))
+	err = vkError(vk.CreateSwapchain(a.logicalDevice, &createInfo, nil, &swapChain))
 	if err != nil {
 		return err
 	}
 
 	a.swapChain = swapChain
-
-	var imagesCount uint32
-	vk.GetSwapchainImages(a.logicalDevice, a.swapChain, &imagesCount, nil)
-	a.swapChainImages = make([]vk.Image, imageCount)
-	vk.GetSwapchainImages(a.logicalDevice, a.swapChain, &imagesCount, a.swapChainImages)
+	a.swapChainDeletionQueue.push(a.leaks.track("swapchain", swapChain, func() {
+		vk.DestroySwapchain(a.logicalDevice, swapChain, nil)
+	}))
+
+	// The implementation may create more images than MinImageCount asked
+	// for, size the slice from the count it reports.
+	err = enumerate(func() vk.Result {
+		var imagesCount uint32
+		res := vk.GetSwapchainImages(a.logicalDevice, a.swapChain, &imagesCount, nil)
+		if res != vk.Success {
+			return res
+		}
+		a.swapChainImages = make([]vk.Image, imagesCount)
+		res = vk.GetSwapchainImages(a.logicalDevice, a.swapChain, &imagesCount, a.swapChainImages)
+		a.swapChainImages = a.swapChainImages[:imagesCount]
+		return res
+	})
+	if err != nil {
+		return err
+	}
 
 	a.swapChainExtent = swapExtent
 	a.swapChainImageFormat = surfaceFormat.Format
@@ -618,49 +875,91 @@ func (a *app) createSwapChain() error {
 
 func (a *app) createInstance() error {
 
-	requiredExtensions := a.window.GetRequiredInstanceExtensions()
+	requiredExtensions := append(a.window.GetRequiredInstanceExtensions(), a.config.RequiredInstanceExtensions...)
 	if a.config.EnableValidationLayers {
 		requiredExtensions = append(requiredExtensions, "VK_EXT_debug_report\x00")
 	}
-	err := checkExtensionSupport(requiredExtensions)
+	optionalExtensions := append(a.portabilityInstanceExtensions(), fullScreenExclusiveInstanceExtensions()...)
+	optionalExtensions = append(optionalExtensions, a.config.OptionalInstanceExtensions...)
+	supportedExtensions, err := supportedInstanceExtensions()
 	if err != nil {
 		return err
 	}
+	extensions, missingExtensions := negotiateNames(supportedExtensions, requiredExtensions, optionalExtensions)
+
+	var requiredLayers []string
+	if a.config.EnableValidationLayers {
+		requiredLayers = a.config.ValidationLayers
+	}
+	supported, err := supportedLayers()
+	if err != nil {
+		return err
+	}
+	layers, missingLayers := negotiateNames(supported, requiredLayers, a.config.OptionalLayers)
+
+	if len(missingExtensions) > 0 || len(missingLayers) > 0 {
+		e := &Error{Stage: StageInstance, Err: fmt.Errorf("failed to create instance")}
+		if len(missingLayers) > 0 {
+			e.Causes = append(e.Causes, &Error{Err: ErrMissingLayers, Missing: missingLayers})
+		}
+		if len(missingExtensions) > 0 {
+			e.Causes = append(e.Causes, &Error{Err: ErrMissingInstanceExtensions, Missing: missingExtensions})
+		}
+		return e
+	}
+
+	// A 1.0 loader rejects any other apiVersion, newer ones accept any but
+	// only provide up to their own.
+	loaderVersion, err := vkext.EnumerateInstanceVersion()
+	if err != nil {
+		return err
+	}
+	apiVersion := a.config.APIVersion
+	if loaderVersion < apiVersion {
+		if loaderVersion < a.config.MinAPIVersion {
+			return &Error{Stage: StageInstance, Err: fmt.Errorf("%w: the loader supports Vulkan %s, %s required", ErrUnsupportedAPIVersion, versionString(loaderVersion), versionString(a.config.MinAPIVersion))}
+		}
+		apiVersion = loaderVersion
+	}
 
 	applicationInfo := vk.ApplicationInfo{
 		SType:              vk.StructureTypeApplicationInfo,
-		PApplicationName:   "Hello Triangle",
-		ApplicationVersion: vk.MakeVersion(1, 0, 0),
-		PEngineName:        "No Engine",
-		EngineVersion:      vk.MakeVersion(1, 0, 0),
-		ApiVersion:         vk.MakeVersion(1, 0, 0),
+		PApplicationName:   nullTerminate(a.config.ApplicationName),
+		ApplicationVersion: a.config.ApplicationVersion,
+		PEngineName:        nullTerminate(a.config.EngineName),
+		EngineVersion:      a.config.EngineVersion,
+		ApiVersion:         apiVersion,
 	}
 
 	dbgCreateInfo := defaultDebugCreateInfo()
 	instanceCreateInfo := vk.InstanceCreateInfo{
 		SType:                   vk.StructureTypeInstanceCreateInfo,
+		Flags:                   portabilityInstanceFlags(extensions),
 		PApplicationInfo:        &applicationInfo,
-		EnabledExtensionCount:   uint32(len(requiredExtensions)),
-		PpEnabledExtensionNames: requiredExtensions,
+		EnabledExtensionCount:   uint32(len(extensions)),
+		PpEnabledExtensionNames: nullTerminateAll(extensions),
+		EnabledLayerCount:       uint32(len(layers)),
+		PpEnabledLayerNames:     nullTerminateAll(layers),
 		PNext:                   unsafe.Pointer(dbgCreateInfo.Ref()),
 	}
 
-	if a.config.EnableValidationLayers {
-		err := checkValidationLayerSupport(a.config.ValidationLayers)
-		if err != nil {
-			return err
-		}
-		instanceCreateInfo.PpEnabledLayerNames = a.config.ValidationLayers
-		instanceCreateInfo.EnabledLayerCount = uint32(len(a.config.ValidationLayers))
-	}
-
 	var instance vk.Instance
 	res := vk.CreateInstance(&instanceCreateInfo, nil, &instance)
+	if res == vk.ErrorIncompatibleDriver {
+		return &Error{Stage: StageInstance, Result: res, Err: fmt.Errorf("%w: Vulkan %s is not supported by the loader", ErrUnsupportedAPIVersion, versionString(applicationInfo.ApiVersion))}
+	}
 	if res != vk.Success {
-		return fmt.Errorf("failed to create instance")
+		return vkErrorf(res, "failed to create instance")
 	}
 
 	a.instance = instance
+	a.deletionQueue.push(a.leaks.track("instance", instance, func() {
+		vk.DestroyInstance(instance, nil)
+	}))
+	a.instanceAPIVersion = apiVersion
+	a.instanceExtensions = extensions
+	a.instanceLayers = layers
+	a.instanceFuncs = vkext.LoadInstance(instance, apiVersion, extensions)
 
 	return nil
 }
@@ -672,13 +971,20 @@ func (a *app) createWindowSurface() error {
 		return err
 	}
 
-	a.windowSurface = vk.SurfaceFromPointer(surfaceAddr)
+	surface := vk.SurfaceFromPointer(surfaceAddr)
+	a.windowSurface = surface
+	a.deletionQueue.push(a.leaks.track("surface", surface, func() {
+		vk.DestroySurface(a.instance, surface, nil)
+	}))
 
 	return nil
 }
 
 func (a *app) createLogicalDevice() error {
-	indices := findQueueFamilies(a.physicalDevice, a.windowSurface)
+	indices, err := findQueueFamilies(a.physicalDevice, a.windowSurface)
+	if err != nil {
+		return err
+	}
 
 	uniqueQueueFamily := map[uint32]bool{
 		*indices.graphicsFamily: true,
@@ -695,27 +1001,52 @@ func (a *app) createLogicalDevice() error {
 		})
 	}
 
-	//deviceFeatures := []vk.PhysicalDeviceFeatures{}
+	deviceFeatures := []vk.PhysicalDeviceFeatures{a.enabledFeatures}
+
+	supportedExtensions, err := supportedDeviceExtensions(a.physicalDevice)
+	if err != nil {
+		return err
+	}
+
+	// Required extensions were checked by pickPhysicalDevice, and so were
+	// those providing the enabled features.
+	optionalExtensions := append(portabilityDeviceExtensions(supportedExtensions), a.config.OptionalDeviceExtensions...)
+	optionalExtensions = append(optionalExtensions, a.enabledFeatures2.Extensions(a.apiVersion)...)
+	optionalExtensions = append(optionalExtensions, a.fullScreenExclusiveDeviceExtensions(supportedExtensions)...)
+	extensions, _ := negotiateNames(supportedExtensions, a.config.RequiredDeviceExtensions, optionalExtensions)
+
+	deviceFeatures2 := vkext.NewDeviceFeatures(a.enabledFeatures2, a.apiVersion, extensions)
+	defer deviceFeatures2.Free()
 
+	// Device layers are deprecated, they are only set for older
+	// implementations and must match the instance layers.
 	deviceCreateInfo := vk.DeviceCreateInfo{
 		SType:                   vk.StructureTypeDeviceCreateInfo,
 		QueueCreateInfoCount:    uint32(len(queueCreateInfos)),
 		PQueueCreateInfos:       queueCreateInfos,
-		EnabledExtensionCount:   uint32(len(a.config.RequiredDeviceExtensions)),
-		PpEnabledExtensionNames: a.config.RequiredDeviceExtensions,
-	}
-
-	if a.config.EnableValidationLayers {
-		deviceCreateInfo.EnabledLayerCount = uint32(len(a.config.ValidationLayers))
-		deviceCreateInfo.PpEnabledLayerNames = a.config.ValidationLayers
+		EnabledExtensionCount:   uint32(len(extensions)),
+		PpEnabledExtensionNames: nullTerminateAll(extensions),
+		EnabledLayerCount:       uint32(len(a.instanceLayers)),
+		PpEnabledLayerNames:     nullTerminateAll(a.instanceLayers),
+		PEnabledFeatures:        deviceFeatures,
+		PNext:                   deviceFeatures2.Ref(),
 	}
 
 	var device vk.Device
-	if vk.CreateDevice(a.physicalDevice, &deviceCreateInfo, nil, &device) != vk.Success {
-		return fmt.Errorf("could not create logical device")
+	err = vkErrorf(vk.CreateDevice(a.physicalDevice, &deviceCreateInfo, nil, &device), "could not create logical device")
+	if err != nil {
+		return err
 	}
 
+	a.deviceExtensions = extensions
+
 	a.logicalDevice = device
+	a.deletionQueue.push(a.leaks.track("device", device, func() {
+		vk.DestroyDevice(device, nil)
+	}))
+	a.deviceFuncs = a.instanceFuncs.LoadDevice(device, a.apiVersion, extensions)
+	a.dynamicRendering = a.enabledFeatures2.Vulkan13.DynamicRendering == vk.True && a.deviceFuncs.DynamicRendering() &&
+		a.enabledFeatures2.Vulkan13.Synchronization2 == vk.True && a.deviceFuncs.Synchronization2()
 
 	var graphicsQueue vk.Queue
 	vk.GetDeviceQueue(device, *indices.graphicsFamily, 0, &graphicsQueue)
@@ -727,26 +1058,82 @@ func (a *app) createLogicalDevice() error {
 
 	a.presentQueue = presentQueue
 
+	a.logRenderingPath()
+	a.logFullScreenExclusive()
+
 	return nil
 }
 
-func (a *app) isDeviceSuitable(device vk.PhysicalDevice) bool {
+func (a *app) createAllocator() {
+	config := a.config.Memory
+	if config.QueryBudget == nil && a.HasDeviceExtension(memoryBudgetExtension) {
+		config.QueryBudget = a.queryMemoryBudget
+	}
+
+	allocator := memory.New(a.logicalDevice, a.physicalDevice, config)
+	a.allocator = allocator
+	a.deletionQueue.push(a.leaks.track("memory allocator", allocator, func() {
+		if stats := allocator.Stats(); a.leaks != nil && stats.Total.Allocations > 0 {
+			log.Printf("[LEAK] %d memory allocations were not freed: %s", stats.Total.Allocations, stats.Total)
+		}
+		allocator.Destroy()
+	}))
+}
+
+// logRenderingPath reports whether frames are drawn with dynamic rendering
+// and synchronization2, from Vulkan 1.3 or their extensions, or with a render
+// pass and framebuffers on devices without them.
+func (a *app) logRenderingPath() {
+	if a.dynamicRendering {
+		log.Printf("using dynamic rendering and synchronization2")
+	} else {
+		log.Printf("device lacks dynamic rendering or synchronization2, using render passes")
+	}
+}
+
+// checkDeviceSuitability returns an error describing why device can't be used,
+// or nil if it can.
+func (a *app) checkDeviceSuitability(device vk.PhysicalDevice) error {
+	properties := getDeviceProperties(device)
+	if properties.ApiVersion < a.config.MinAPIVersion {
+		return fmt.Errorf("%w: supports %s, %s required", ErrUnsupportedAPIVersion, versionString(properties.ApiVersion), versionString(a.config.MinAPIVersion))
+	}
 
-	if !checkDeviceExtensionsSupport(device, a.config.RequiredDeviceExtensions) {
-		return false
+	missingExtensions, err := missingDeviceExtensions(device, a.config.RequiredDeviceExtensions)
+	if err != nil {
+		return err
+	}
+	if len(missingExtensions) > 0 {
+		return &Error{Err: ErrMissingDeviceExtensions, Missing: missingExtensions}
 	}
 
-	swapChainSupport := querySwapChainSupport(device, a.windowSurface)
+	supportedExtensions, err := supportedDeviceExtensions(device)
+	if err != nil {
+		return err
+	}
+	features, features2 := a.getDeviceFeatures(device, supportedExtensions)
+	missing := append(missingFeatures(&features, &a.config.RequiredFeatures), missingFeatures(&features2, &a.config.RequiredFeatures2)...)
+	if len(missing) > 0 {
+		return &Error{Err: ErrMissingFeatures, Missing: missing}
+	}
+
+	swapChainSupport, err := querySwapChainSupport(device, a.windowSurface)
+	if err != nil {
+		return err
+	}
 	if len(swapChainSupport.surfaceFormats) == 0 || len(swapChainSupport.presentationModes) == 0 {
-		return false
+		return ErrNoSurfaceSupport
 	}
 
-	indices := findQueueFamilies(device, a.windowSurface)
+	indices, err := findQueueFamilies(device, a.windowSurface)
+	if err != nil {
+		return err
+	}
 	if !indices.isComplete() {
-		return false
+		return ErrNoQueueFamilies
 	}
 
-	return true
+	return nil
 }
 
 func chooseSwapSurfaceFormat(surfaceFormats ...vk.SurfaceFormat) vk.SurfaceFormat {
@@ -821,36 +1208,75 @@ func chooseSwapExtent(surfaceCapabilities vk.SurfaceCapabilities, win *glfw.Wind
 func (a *app) setupDebugMessenger() error {
 	dbgCreateInfo := defaultDebugCreateInfo()
 	var dbg vk.DebugReportCallback
-	err := vk.Error(vk.CreateDebugReportCallback(a.instance, &dbgCreateInfo, nil, &dbg))
+	err := vkErrorf(vk.CreateDebugReportCallback(a.instance, &dbgCreateInfo, nil, &dbg), "vk.CreateDebugReportCallback failed")
 	if err != nil {
-		err = fmt.Errorf("vk.CreateDebugReportCallback failed with %s", err)
 		return err
 	}
 	a.debugMessenger = dbg
+	a.deletionQueue.push(a.leaks.track("debug report callback", dbg, func() {
+		vk.DestroyDebugReportCallback(a.instance, dbg, nil)
+	}))
 	return nil
 }
 
 func (a *app) pickPhysicalDevice() error {
 
-	var deviceCount uint32
-	vk.EnumeratePhysicalDevices(a.instance, &deviceCount, nil)
-	if deviceCount == 0 {
-		return fmt.Errorf("failed to find gpus with vulkan support")
+	var physicalDevices []vk.PhysicalDevice
+	err := enumerate(func() vk.Result {
+		var deviceCount uint32
+		res := vk.EnumeratePhysicalDevices(a.instance, &deviceCount, nil)
+		if res != vk.Success {
+			return res
+		}
+		physicalDevices = make([]vk.PhysicalDevice, deviceCount)
+		res = vk.EnumeratePhysicalDevices(a.instance, &deviceCount, physicalDevices)
+		physicalDevices = physicalDevices[:deviceCount]
+		return res
+	})
+	if err != nil {
+		return err
+	}
+	if len(physicalDevices) == 0 {
+		return ErrNoVulkanDevice
 	}
 
-	physicalDevices := make([]vk.PhysicalDevice, deviceCount)
-	vk.EnumeratePhysicalDevices(a.instance, &deviceCount, physicalDevices)
-
+	var rejections []error
 	for _, physicalDevice := range physicalDevices {
-		if a.isDeviceSuitable(physicalDevice) {
+		err := a.checkDeviceSuitability(physicalDevice)
+		a.logDeviceSelection(physicalDevice, err)
+		if err == nil {
 			a.physicalDevice = physicalDevice
 			break
 		}
+
+		properties := getDeviceProperties(physicalDevice)
+		rejection := &Error{Device: vk.ToString(properties.DeviceName[:]), Err: err}
+		var e *Error
+		if errors.As(err, &e) {
+			e.Device = rejection.Device
+			rejection = e
+		}
+		rejections = append(rejections, rejection)
 	}
 
 	if unsafe.Pointer(a.physicalDevice) == vk.NullHandle {
-		return fmt.Errorf("failed to find a suitable gpu")
+		return &Error{Stage: StageDeviceSelection, Err: ErrNoSuitableDevice, Causes: rejections}
+	}
+
+	supportedExtensions, err := supportedDeviceExtensions(a.physicalDevice)
+	if err != nil {
+		return err
 	}
+	features, features2 := a.getDeviceFeatures(a.physicalDevice, supportedExtensions)
+	a.apiVersion = a.deviceAPIVersion(a.physicalDevice)
+	enableFeatures(&a.enabledFeatures, &features, &a.config.RequiredFeatures, &a.config.OptionalFeatures)
+	// The frame loop uses timeline semaphores, dynamic rendering and
+	// synchronization2 when the device has them.
+	optionalFeatures2 := a.config.OptionalFeatures2
+	optionalFeatures2.Vulkan12.TimelineSemaphore = vk.True
+	optionalFeatures2.Vulkan13.DynamicRendering = vk.True
+	optionalFeatures2.Vulkan13.Synchronization2 = vk.True
+	enableFeatures(&a.enabledFeatures2, &features2, &a.config.RequiredFeatures2, &optionalFeatures2)
 
 	return nil
 }
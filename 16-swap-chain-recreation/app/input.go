@@ -0,0 +1,210 @@
+package app
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// Input records keyboard, mouse and gamepad state for the current frame. It
+// is filled in by GLFW callbacks while events are polled and reset at the
+// start of every main loop iteration.
+type Input struct {
+	window *glfw.Window
+
+	keysHeld     map[glfw.Key]bool
+	keysPressed  map[glfw.Key]bool
+	keysReleased map[glfw.Key]bool
+
+	buttonsHeld     map[glfw.MouseButton]bool
+	buttonsPressed  map[glfw.MouseButton]bool
+	buttonsReleased map[glfw.MouseButton]bool
+
+	cursorX, cursorY           float64
+	cursorDeltaX, cursorDeltaY float64
+	hasCursorPos               bool
+	scrollX, scrollY           float64
+	cursorCaptured             bool
+
+	gamepads     map[glfw.Joystick]glfw.GamepadState
+	prevGamepads map[glfw.Joystick]glfw.GamepadState
+}
+
+func newInput(window *glfw.Window) *Input {
+	in := &Input{
+		window:          window,
+		keysHeld:        make(map[glfw.Key]bool),
+		keysPressed:     make(map[glfw.Key]bool),
+		keysReleased:    make(map[glfw.Key]bool),
+		buttonsHeld:     make(map[glfw.MouseButton]bool),
+		buttonsPressed:  make(map[glfw.MouseButton]bool),
+		buttonsReleased: make(map[glfw.MouseButton]bool),
+		gamepads:        make(map[glfw.Joystick]glfw.GamepadState),
+		prevGamepads:    make(map[glfw.Joystick]glfw.GamepadState),
+	}
+
+	window.SetKeyCallback(func(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+		switch action {
+		case glfw.Press:
+			in.keysHeld[key] = true
+			in.keysPressed[key] = true
+		case glfw.Release:
+			delete(in.keysHeld, key)
+			in.keysReleased[key] = true
+		}
+	})
+
+	window.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+		switch action {
+		case glfw.Press:
+			in.buttonsHeld[button] = true
+			in.buttonsPressed[button] = true
+		case glfw.Release:
+			delete(in.buttonsHeld, button)
+			in.buttonsReleased[button] = true
+		}
+	})
+
+	window.SetCursorPosCallback(func(w *glfw.Window, x float64, y float64) {
+		if in.hasCursorPos {
+			in.cursorDeltaX += x - in.cursorX
+			in.cursorDeltaY += y - in.cursorY
+		}
+		in.cursorX, in.cursorY = x, y
+		in.hasCursorPos = true
+	})
+
+	window.SetScrollCallback(func(w *glfw.Window, xoff float64, yoff float64) {
+		in.scrollX += xoff
+		in.scrollY += yoff
+	})
+
+	return in
+}
+
+// beginFrame clears the per-frame state. It must be called before events are
+// polled.
+func (in *Input) beginFrame() {
+	for k := range in.keysPressed {
+		delete(in.keysPressed, k)
+	}
+	for k := range in.keysReleased {
+		delete(in.keysReleased, k)
+	}
+	for b := range in.buttonsPressed {
+		delete(in.buttonsPressed, b)
+	}
+	for b := range in.buttonsReleased {
+		delete(in.buttonsReleased, b)
+	}
+
+	in.cursorDeltaX, in.cursorDeltaY = 0, 0
+	in.scrollX, in.scrollY = 0, 0
+}
+
+// pollGamepads snapshots every connected gamepad. GLFW has no gamepad
+// callbacks so this is called once per frame after events are polled.
+func (in *Input) pollGamepads() {
+	in.prevGamepads, in.gamepads = in.gamepads, in.prevGamepads
+	for j := range in.gamepads {
+		delete(in.gamepads, j)
+	}
+
+	for j := glfw.Joystick1; j <= glfw.JoystickLast; j++ {
+		if !j.Present() || !j.IsGamepad() {
+			continue
+		}
+
+		state := j.GetGamepadState()
+		if state != nil {
+			in.gamepads[j] = *state
+		}
+	}
+}
+
+// KeyDown reports whether key is currently held.
+func (in *Input) KeyDown(key glfw.Key) bool {
+	return in.keysHeld[key]
+}
+
+// KeyPressed reports whether key went down during this frame.
+func (in *Input) KeyPressed(key glfw.Key) bool {
+	return in.keysPressed[key]
+}
+
+// KeyReleased reports whether key went up during this frame.
+func (in *Input) KeyReleased(key glfw.Key) bool {
+	return in.keysReleased[key]
+}
+
+func (in *Input) MouseButtonDown(button glfw.MouseButton) bool {
+	return in.buttonsHeld[button]
+}
+
+func (in *Input) MouseButtonPressed(button glfw.MouseButton) bool {
+	return in.buttonsPressed[button]
+}
+
+func (in *Input) MouseButtonReleased(button glfw.MouseButton) bool {
+	return in.buttonsReleased[button]
+}
+
+// CursorPos returns the cursor position in screen coordinates relative to the
+// top-left corner of the window.
+func (in *Input) CursorPos() (x, y float64) {
+	return in.cursorX, in.cursorY
+}
+
+// CursorDelta returns how far the cursor moved during this frame.
+func (in *Input) CursorDelta() (dx, dy float64) {
+	return in.cursorDeltaX, in.cursorDeltaY
+}
+
+// Scroll returns the scroll offset accumulated during this frame.
+func (in *Input) Scroll() (x, y float64) {
+	return in.scrollX, in.scrollY
+}
+
+// SetCursorCaptured hides the cursor and locks it to the window, which is what
+// FPS-style cameras want. Raw mouse motion is used when the platform has it.
+func (in *Input) SetCursorCaptured(captured bool) {
+	if captured {
+		in.window.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
+		if glfw.RawMouseMotionSupported() {
+			in.window.SetInputMode(glfw.RawMouseMotion, glfw.True)
+		}
+	} else {
+		in.window.SetInputMode(glfw.CursorMode, glfw.CursorNormal)
+		if glfw.RawMouseMotionSupported() {
+			in.window.SetInputMode(glfw.RawMouseMotion, glfw.False)
+		}
+	}
+
+	in.cursorCaptured = captured
+	// The cursor jumps when the mode changes, don't report that as motion.
+	in.hasCursorPos = false
+}
+
+func (in *Input) CursorCaptured() bool {
+	return in.cursorCaptured
+}
+
+// Gamepad returns the state of joystick if it is connected and has a gamepad
+// mapping.
+func (in *Input) Gamepad(joystick glfw.Joystick) (glfw.GamepadState, bool) {
+	state, ok := in.gamepads[joystick]
+	return state, ok
+}
+
+func (in *Input) GamepadButtonDown(joystick glfw.Joystick, button glfw.GamepadButton) bool {
+	state, ok := in.gamepads[joystick]
+	return ok && state.Buttons[button] == glfw.Press
+}
+
+// GamepadButtonPressed reports whether button went down during this frame.
+func (in *Input) GamepadButtonPressed(joystick glfw.Joystick, button glfw.GamepadButton) bool {
+	prev := in.prevGamepads[joystick]
+	return in.GamepadButtonDown(joystick, button) && prev.Buttons[button] != glfw.Press
+}
+
+func (in *Input) GamepadAxis(joystick glfw.Joystick, axis glfw.GamepadAxis) float32 {
+	return in.gamepads[joystick].Axes[axis]
+}
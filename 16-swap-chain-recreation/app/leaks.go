@@ -0,0 +1,105 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxStackDepth bounds the call stack recorded for each tracked handle.
+const maxStackDepth = 32
+
+type trackedHandle struct {
+	kind   string
+	handle interface{}
+	order  uint64
+	stack  []uintptr
+}
+
+// leakTracker records every live Vulkan handle together with the Go call
+// stack that created it. A nil *leakTracker is valid and tracks nothing, so
+// call sites don't need to check whether AppConfig.TrackLeaks is set.
+type leakTracker struct {
+	mu      sync.Mutex
+	handles map[interface{}]*trackedHandle
+	created uint64
+}
+
+func newLeakTracker() *leakTracker {
+	return &leakTracker{handles: make(map[interface{}]*trackedHandle)}
+}
+
+// track records handle as live and returns destroy wrapped so it marks the
+// handle destroyed first. The stack is taken from track's caller, call it
+// right after creating the handle.
+func (t *leakTracker) track(kind string, handle interface{}, destroy func()) func() {
+	if t == nil {
+		return destroy
+	}
+
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(2, pcs)
+
+	t.mu.Lock()
+	t.created++
+	t.handles[handle] = &trackedHandle{kind: kind, handle: handle, order: t.created, stack: pcs[:n]}
+	t.mu.Unlock()
+
+	return func() {
+		t.untrack(kind, handle)
+		destroy()
+	}
+}
+
+func (t *leakTracker) untrack(kind string, handle interface{}) {
+	t.mu.Lock()
+	_, ok := t.handles[handle]
+	delete(t.handles, handle)
+	t.mu.Unlock()
+
+	if !ok {
+		pcs := make([]uintptr, maxStackDepth)
+		n := runtime.Callers(3, pcs)
+		log.Printf("[LEAK] %s %v destroyed twice or never tracked, destroyed at:\n%s", kind, handle, formatStack(pcs[:n]))
+	}
+}
+
+// report logs every handle that is still alive, oldest first, and returns
+// how many there were.
+func (t *leakTracker) report() int {
+	if t == nil {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	live := make([]*trackedHandle, 0, len(t.handles))
+	for _, tracked := range t.handles {
+		live = append(live, tracked)
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].order < live[j].order })
+
+	for _, tracked := range live {
+		log.Printf("[LEAK] %s %v was never destroyed, created at:\n%s", tracked.kind, tracked.handle, formatStack(tracked.stack))
+	}
+
+	return len(live)
+}
+
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "\t%s\n\t\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"log"
+	"vulkan-tutorial-go/16-swap-chain-recreation/memory"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+const memoryBudgetExtension = "VK_EXT_memory_budget"
+
+// memoryWarnFraction is the share of a heap's budget above which the memory
+// report warns.
+const memoryWarnFraction = 0.9
+
+// MemoryBudget returns the budget and usage of every memory heap, see
+// memory.Allocator.Budget for where the numbers come from.
+func (a *app) MemoryBudget() []memory.HeapBudget {
+	return a.allocator.Budget()
+}
+
+// reportMemory logs the memory budget every MemoryReportInterval.
+func (a *app) reportMemory() {
+	interval := a.config.MemoryReportInterval.Seconds()
+	if interval <= 0 || a.clock.Time()-a.lastMemoryReport < interval {
+		return
+	}
+	a.lastMemoryReport = a.clock.Time()
+
+	for _, heap := range a.MemoryBudget() {
+		kind := "host"
+		if heap.DeviceLocal {
+			kind = "device local"
+		}
+
+		format := "heap %d (%s, %s): %s of %s budget used (%.0f%%)"
+		if heap.Fraction() >= memoryWarnFraction {
+			format = "[WARN] " + format
+		}
+		log.Printf(format, heap.Heap, kind, memory.FormatBytes(heap.Size),
+			memory.FormatBytes(heap.Usage), memory.FormatBytes(heap.Budget), heap.Fraction()*100)
+	}
+
+	stats := a.allocator.Stats()
+	log.Printf("memory: %s, %d of %d device allocations", stats.Total, stats.DeviceAllocations, stats.MaxDeviceAllocations)
+}
+
+// queryMemoryBudget is the memory.Config.QueryBudget of the allocator when
+// VK_EXT_memory_budget is enabled.
+func (a *app) queryMemoryBudget() (budget, usage []vk.DeviceSize, ok bool) {
+	return a.instanceFuncs.MemoryBudget(a.physicalDevice)
+}
+
+// logMemoryBudgetSource notes when budgets are estimates because
+// VK_EXT_memory_budget can't be used.
+func (a *app) logMemoryBudgetSource() {
+	budgets := a.MemoryBudget()
+	if len(budgets) > 0 && budgets[0].Estimated {
+		log.Printf("%s is unavailable; memory budgets are estimated from heap sizes", memoryBudgetExtension)
+	}
+}
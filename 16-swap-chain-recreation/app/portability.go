@@ -0,0 +1,87 @@
+package app
+
+import (
+	"log"
+	"strings"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+const (
+	portabilityEnumerationExtension = "VK_KHR_portability_enumeration"
+	portabilitySubsetExtension      = "VK_KHR_portability_subset"
+	// portabilitySubset depends on it, and it is core in Vulkan 1.1.
+	physicalDeviceProperties2Extension = "VK_KHR_get_physical_device_properties2"
+
+	// instanceCreateEnumeratePortabilityBit is
+	// VK_INSTANCE_CREATE_ENUMERATE_PORTABILITY_BIT_KHR, which is newer than
+	// the headers the bindings were generated from.
+	instanceCreateEnumeratePortabilityBit vk.InstanceCreateFlags = 0x00000001
+)
+
+// portabilityInstanceExtensions returns the optional instance extensions that
+// make portability-subset implementations (MoltenVK, some layered drivers)
+// visible to vkEnumeratePhysicalDevices on loaders 1.3.216 and newer.
+func (a *app) portabilityInstanceExtensions() []string {
+	if a.config.DisablePortabilityEnumeration {
+		return nil
+	}
+
+	return []string{portabilityEnumerationExtension, physicalDeviceProperties2Extension}
+}
+
+// portabilityInstanceFlags returns the instance create flags needed for the
+// enabled portability extensions.
+func portabilityInstanceFlags(enabledExtensions []string) vk.InstanceCreateFlags {
+	if containsName(enabledExtensions, portabilityEnumerationExtension) {
+		return instanceCreateEnumeratePortabilityBit
+	}
+	return 0
+}
+
+// portabilityDeviceExtensions returns the extensions a device advertising
+// VK_KHR_portability_subset must have enabled. The spec requires enabling it
+// whenever it is present. supported is the set of extensions the device
+// advertises.
+func portabilityDeviceExtensions(supported map[string]bool) []string {
+	if !supported[portabilitySubsetExtension] {
+		return nil
+	}
+	return []string{portabilitySubsetExtension}
+}
+
+// logDeviceSelection reports a candidate device and why it was or wasn't
+// picked. For portability-subset implementations it also lists the
+// VK_KHR_portability_subset features the device has and lacks.
+func (a *app) logDeviceSelection(device vk.PhysicalDevice, err error) {
+	properties := getDeviceProperties(device)
+	name := vk.ToString(properties.DeviceName[:])
+
+	if err != nil {
+		log.Printf("gpu %s (Vulkan %s) rejected: %s", name, versionString(properties.ApiVersion), err)
+		return
+	}
+
+	log.Printf("gpu %s (Vulkan %s) selected", name, versionString(properties.ApiVersion))
+	if extensions, err := supportedDeviceExtensions(device); err != nil || !extensions[portabilitySubsetExtension] {
+		return
+	}
+
+	features, ok := a.instanceFuncs.PortabilitySubsetFeatures(device, a.deviceAPIVersion(device))
+	if !ok {
+		log.Printf("gpu %s is a %s implementation, vkGetPhysicalDeviceFeatures2 is unavailable so its limits are unknown", name, portabilitySubsetExtension)
+		return
+	}
+
+	var supported, unsupported []string
+	eachFeature(&features, func(feature string, value *vk.Bool32) {
+		if *value == vk.True {
+			supported = append(supported, feature)
+		} else {
+			unsupported = append(unsupported, feature)
+		}
+	})
+	log.Printf("gpu %s is a %s implementation", name, portabilitySubsetExtension)
+	log.Printf("  supported: %s", strings.Join(supported, ", "))
+	log.Printf("  unsupported: %s", strings.Join(unsupported, ", "))
+}
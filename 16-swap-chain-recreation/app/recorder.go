@@ -0,0 +1,176 @@
+package app
+
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+	"vulkan-tutorial-go/16-swap-chain-recreation/vkext"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// SecondaryRecordFunc records one worker's share of a frame into a secondary
+// command buffer. It runs concurrently on RecordWorkers goroutines, worker is
+// in the range 0..RecordWorkers-1. The graphics pipeline is already bound.
+type SecondaryRecordFunc func(worker int, commandBuffer vk.CommandBuffer, extent vk.Extent2D) error
+
+type recordJob struct {
+	frame       int
+	framebuffer vk.Framebuffer
+	extent      vk.Extent2D
+	wg          *sync.WaitGroup
+}
+
+// recordWorker owns a command pool per frame in flight. Command pools must be
+// externally synchronized, so each worker only ever touches its own pools and
+// does so from a goroutine locked to one OS thread.
+type recordWorker struct {
+	index          int
+	commandPools   []vk.CommandPool
+	commandBuffers []vk.CommandBuffer
+	jobs           chan recordJob
+	err            error
+}
+
+func (a *app) createRecordWorkers() error {
+	if a.config.RecordWorkers <= 0 || a.config.RecordSecondary == nil {
+		return nil
+	}
+
+	indices, err := findQueueFamilies(a.physicalDevice, a.windowSurface)
+	if err != nil {
+		return err
+	}
+
+	commandPoolCreateInfo := vk.CommandPoolCreateInfo{
+		SType:            vk.StructureTypeCommandPoolCreateInfo,
+		PNext:            nil,
+		Flags:            vk.CommandPoolCreateFlags(vk.CommandPoolCreateTransientBit),
+		QueueFamilyIndex: *indices.graphicsFamily,
+	}
+
+	a.recordWorkers = make([]*recordWorker, a.config.RecordWorkers)
+	for i := range a.recordWorkers {
+		w := &recordWorker{
+			index:          i,
+			commandPools:   make([]vk.CommandPool, maxFramesInFlight),
+			commandBuffers: make([]vk.CommandBuffer, maxFramesInFlight),
+			jobs:           make(chan recordJob),
+		}
+		a.recordWorkers[i] = w
+		a.deletionQueue.push(func() {
+			close(w.jobs)
+		})
+
+		for frame := 0; frame < maxFramesInFlight; frame++ {
+			var commandPool vk.CommandPool
+			err = vkError(vk.CreateCommandPool(a.logicalDevice, &commandPoolCreateInfo, nil, &commandPool))
+			if err != nil {
+				return err
+			}
+			w.commandPools[frame] = commandPool
+			a.deletionQueue.push(a.leaks.track("command pool", commandPool, func() {
+				vk.DestroyCommandPool(a.logicalDevice, commandPool, nil)
+			}))
+
+			allocateInfo := vk.CommandBufferAllocateInfo{
+				SType:              vk.StructureTypeCommandBufferAllocateInfo,
+				CommandPool:        commandPool,
+				Level:              vk.CommandBufferLevelSecondary,
+				CommandBufferCount: 1,
+			}
+			commandBuffers := make([]vk.CommandBuffer, 1)
+			err = vkError(vk.AllocateCommandBuffers(a.logicalDevice, &allocateInfo, commandBuffers))
+			if err != nil {
+				return err
+			}
+			w.commandBuffers[frame] = commandBuffers[0]
+		}
+
+		go a.runRecordWorker(w)
+	}
+
+	return nil
+}
+
+func (a *app) runRecordWorker(w *recordWorker) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	for job := range w.jobs {
+		w.err = a.recordSecondary(w, job)
+		job.wg.Done()
+	}
+}
+
+func (a *app) recordSecondary(w *recordWorker, job recordJob) error {
+	err := vkError(vk.ResetCommandPool(a.logicalDevice, w.commandPools[job.frame], 0))
+	if err != nil {
+		return err
+	}
+
+	commandBuffer := w.commandBuffers[job.frame]
+	// Inside dynamic rendering the attachments are inherited instead of a
+	// render pass. Their flags are the rendering's without
+	// RenderingContentsSecondaryCommandBuffers.
+	var renderingInfo unsafe.Pointer
+	if a.dynamicRendering {
+		inheritanceRenderingInfo := vkext.NewInheritanceRenderingInfo(0, []vk.Format{a.swapChainImageFormat}, vk.SampleCount1Bit)
+		defer inheritanceRenderingInfo.Free()
+		renderingInfo = inheritanceRenderingInfo.Ref()
+	}
+
+	inheritanceInfo := vk.CommandBufferInheritanceInfo{
+		SType:       vk.StructureTypeCommandBufferInheritanceInfo,
+		PNext:       renderingInfo,
+		RenderPass:  a.renderPass,
+		Subpass:     0,
+		Framebuffer: job.framebuffer,
+	}
+	beginInfo := vk.CommandBufferBeginInfo{
+		SType:            vk.StructureTypeCommandBufferBeginInfo,
+		Flags:            vk.CommandBufferUsageFlags(vk.CommandBufferUsageOneTimeSubmitBit | vk.CommandBufferUsageRenderPassContinueBit),
+		PInheritanceInfo: []vk.CommandBufferInheritanceInfo{inheritanceInfo},
+	}
+
+	err = vkError(vk.BeginCommandBuffer(commandBuffer, &beginInfo))
+	if err != nil {
+		return err
+	}
+
+	vk.CmdBindPipeline(commandBuffer, vk.PipelineBindPointGraphics, a.graphicsPipeline)
+	a.setViewportAndScissor(commandBuffer)
+	err = a.config.RecordSecondary(w.index, commandBuffer, job.extent)
+	endErr := vkError(vk.EndCommandBuffer(commandBuffer))
+	if err != nil {
+		return err
+	}
+
+	return endErr
+}
+
+// recordSecondaries records every worker's secondary command buffer for frame
+// in parallel and returns them in worker order.
+func (a *app) recordSecondaries(frame int, framebuffer vk.Framebuffer) ([]vk.CommandBuffer, error) {
+	var wg sync.WaitGroup
+	wg.Add(len(a.recordWorkers))
+	for _, w := range a.recordWorkers {
+		w.jobs <- recordJob{
+			frame:       frame,
+			framebuffer: framebuffer,
+			extent:      a.swapChainExtent,
+			wg:          &wg,
+		}
+	}
+	wg.Wait()
+
+	commandBuffers := make([]vk.CommandBuffer, len(a.recordWorkers))
+	for i, w := range a.recordWorkers {
+		if w.err != nil {
+			return nil, w.err
+		}
+		commandBuffers[i] = w.commandBuffers[frame]
+	}
+
+	return commandBuffers, nil
+}
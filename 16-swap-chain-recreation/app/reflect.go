@@ -0,0 +1,54 @@
+package app
+
+import (
+	"fmt"
+	"vulkan-tutorial-go/16-swap-chain-recreation/spirv"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// reflectEntryPoint returns the reflection of the SPIR-V code and of its
+// entry point called name for stage.
+func reflectEntryPoint(code []uint32, name string, stage vk.ShaderStageFlagBits) (*spirv.Module, spirv.EntryPoint, error) {
+	module, err := spirv.Parse(code)
+	if err != nil {
+		return nil, spirv.EntryPoint{}, err
+	}
+
+	entry, ok := module.EntryPoint(name, stage)
+	if !ok {
+		return nil, spirv.EntryPoint{}, fmt.Errorf("%w: no entry point %q for stage %d", ErrInvalidShader, name, stage)
+	}
+	return module, entry, nil
+}
+
+// createDescriptorSetLayouts creates a descriptor set layout for every set
+// the entry points use, including empty ones for unused set numbers below
+// the highest, and registers them on pipelineDeletionQueue.
+func (a *app) createDescriptorSetLayouts(entries ...spirv.EntryPoint) ([]vk.DescriptorSetLayout, error) {
+	sets, err := spirv.SetLayoutBindings(entries...)
+	if err != nil {
+		return nil, err
+	}
+
+	layouts := make([]vk.DescriptorSetLayout, len(sets))
+	for i, bindings := range sets {
+		createInfo := vk.DescriptorSetLayoutCreateInfo{
+			SType:        vk.StructureTypeDescriptorSetLayoutCreateInfo,
+			BindingCount: uint32(len(bindings)),
+			PBindings:    bindings,
+		}
+
+		var layout vk.DescriptorSetLayout
+		err := vkErrorf(vk.CreateDescriptorSetLayout(a.logicalDevice, &createInfo, nil, &layout), "failed to create the layout of descriptor set %d", i)
+		if err != nil {
+			return nil, err
+		}
+		a.pipelineDeletionQueue.push(a.leaks.track("descriptor set layout", layout, func() {
+			vk.DestroyDescriptorSetLayout(a.logicalDevice, layout, nil)
+		}))
+		layouts[i] = layout
+	}
+
+	return layouts, nil
+}
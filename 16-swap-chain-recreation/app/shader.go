@@ -0,0 +1,105 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"vulkan-tutorial-go/16-swap-chain-recreation/spirv"
+)
+
+// shaderSources maps the modules the pipeline loads to the sources they are
+// compiled from.
+var shaderSources = map[string]string{
+	"vert.spv": "shader.vert",
+	"frag.spv": "shader.frag",
+}
+
+// loadShader returns the words of the SPIR-V module called name. It is
+// compiled from source when there is a ShaderCompiler and the source is on
+// ShaderSearchPaths, otherwise loaded from the first of ShaderSearchPaths
+// that has it, or from ShaderFS. Compile errors are logged and the
+// precompiled module is used instead.
+func (a *app) loadShader(name string) ([]uint32, error) {
+	words, err := a.compileShader(name)
+	if err != nil {
+		log.Printf("%s, using the precompiled %s", err, name)
+	} else if words != nil {
+		return words, nil
+	}
+
+	return a.loadPrecompiledShader(name)
+}
+
+// reloadShader is loadShader for hot reloads, compile errors are returned so
+// the running pipeline can be kept.
+func (a *app) reloadShader(name string) ([]uint32, error) {
+	words, err := a.compileShader(name)
+	if err != nil || words != nil {
+		return words, err
+	}
+
+	return a.loadPrecompiledShader(name)
+}
+
+func (a *app) loadPrecompiledShader(name string) ([]uint32, error) {
+	for _, dir := range a.config.ShaderSearchPaths {
+		path := filepath.Join(dir, name)
+		code, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return decodeShader(path, code)
+	}
+
+	code, err := fs.ReadFile(a.config.ShaderFS, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s is not in %v or the embedded shaders", ErrShaderNotFound, name, a.config.ShaderSearchPaths)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeShader(name, code)
+}
+
+// compileShader compiles the source of the module called name. It returns
+// nil words and no error when there is no ShaderCompiler or no source on
+// ShaderSearchPaths.
+func (a *app) compileShader(name string) ([]uint32, error) {
+	source, ok := shaderSources[name]
+	if !ok || a.config.ShaderCompiler == nil {
+		return nil, nil
+	}
+
+	for _, dir := range a.config.ShaderSearchPaths {
+		path := filepath.Join(dir, source)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		code, err := a.config.ShaderCompiler.Compile(path)
+		if err != nil {
+			return nil, fmt.Errorf("compiling %s failed: %w", path, err)
+		}
+
+		return decodeShader(path, code)
+	}
+
+	return nil, nil
+}
+
+// decodeShader decodes the SPIR-V module called name, see spirv.Decode.
+func decodeShader(name string, code []byte) ([]uint32, error) {
+	words, err := spirv.Decode(code)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return words, nil
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// shaderPollInterval is how often the shader watcher looks for changed files.
+const shaderPollInterval = 500 * time.Millisecond
+
+// shaderExtensions are the files the shader watcher looks at: sources,
+// including those with an .hlsl or .glsl suffix and included files, and
+// compiled SPIR-V. Editor backups and other files next to them are ignored.
+var shaderExtensions = map[string]bool{
+	".vert": true, ".frag": true, ".comp": true, ".geom": true, ".tesc": true, ".tese": true,
+	".glsl": true, ".hlsl": true, ".spv": true,
+}
+
+// shaderWatcher notices changes to the shaders in a set of directories by
+// polling their modification times and sizes, which works the same on every
+// platform and needs nothing beyond the standard library.
+type shaderWatcher struct {
+	dirs     []string
+	files    map[string]os.FileInfo
+	lastPoll time.Time
+}
+
+func newShaderWatcher(dirs []string) *shaderWatcher {
+	w := &shaderWatcher{dirs: dirs}
+	w.files = w.snapshot()
+	w.lastPoll = time.Now()
+	return w
+}
+
+// changed reports whether a file was added, removed or modified since the
+// last call. It only looks at the disk every shaderPollInterval.
+func (w *shaderWatcher) changed() bool {
+	if time.Since(w.lastPoll) < shaderPollInterval {
+		return false
+	}
+	w.lastPoll = time.Now()
+
+	files := w.snapshot()
+	changed := len(files) != len(w.files)
+	for path, info := range files {
+		old, ok := w.files[path]
+		if !ok || !old.ModTime().Equal(info.ModTime()) || old.Size() != info.Size() {
+			changed = true
+		}
+	}
+	w.files = files
+
+	return changed
+}
+
+func (w *shaderWatcher) snapshot() map[string]os.FileInfo {
+	files := make(map[string]os.FileInfo)
+	for _, dir := range w.dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !shaderExtensions[filepath.Ext(entry.Name())] {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			files[filepath.Join(dir, entry.Name())] = info
+		}
+	}
+
+	return files
+}
+
+// reloadShaders rebuilds the graphics pipeline when a watched shader
+// changed. If loading or compiling the shaders, or building the pipeline,
+// fails the error is logged and the running pipeline kept.
+func (a *app) reloadShaders() error {
+	if a.shaderWatcher == nil || !a.shaderWatcher.changed() {
+		return nil
+	}
+
+	vertCode, err := a.reloadShader(a.config.VertexShader.Module)
+	if err != nil {
+		log.Printf("[SHADER] %s, keeping the running pipeline", err)
+		return nil
+	}
+	fragCode, err := a.reloadShader(a.config.FragmentShader.Module)
+	if err != nil {
+		log.Printf("[SHADER] %s, keeping the running pipeline", err)
+		return nil
+	}
+
+	// Build the new pipeline on a fresh queue so a failure only tears down
+	// what it created, the old one keeps drawing meanwhile.
+	oldQueue := a.pipelineDeletionQueue
+	oldSetLayouts, oldLayout, oldPipeline := a.descriptorSetLayouts, a.pipelineLayout, a.graphicsPipeline
+	a.pipelineDeletionQueue = deletionQueue{}
+
+	err = a.buildGraphicsPipeline(vertCode, fragCode)
+	if err != nil {
+		a.pipelineDeletionQueue.flush()
+		a.pipelineDeletionQueue = oldQueue
+		a.descriptorSetLayouts, a.pipelineLayout, a.graphicsPipeline = oldSetLayouts, oldLayout, oldPipeline
+		log.Printf("[SHADER] %s, keeping the running pipeline", err)
+		return nil
+	}
+
+	// Frames in flight may still use the old pipeline.
+	err = vkErrorf(vk.DeviceWaitIdle(a.logicalDevice), "failed to wait for the device to go idle")
+	if err != nil {
+		return withStage(StagePipeline, err)
+	}
+	oldQueue.flush()
+	log.Printf("[SHADER] reloaded shaders from %v", a.shaderWatcher.dirs)
+
+	return nil
+}
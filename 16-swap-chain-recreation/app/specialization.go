@@ -0,0 +1,220 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"unsafe"
+	"vulkan-tutorial-go/16-swap-chain-recreation/spirv"
+	"vulkan-tutorial-go/16-swap-chain-recreation/vkext"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// ShaderStageConfig selects the code a pipeline stage runs.
+type ShaderStageConfig struct {
+	// Module is the name of the SPIR-V module, looked up like the default
+	// vert.spv and frag.spv. Stages may share a module that has an entry
+	// point for each.
+	Module string
+	// EntryPoint defaults to main.
+	EntryPoint string
+	// Specialization sets specialization constants, declared with
+	// layout(constant_id = N) in GLSL, so one module can be compiled once
+	// and toggled per pipeline. It is a map[uint32]interface{} from constant
+	// ID to value, or a struct, or pointer to one, whose fields carry the ID
+	// in a `constant_id:"N"` tag. Values are bools, integers or floats.
+	// Sized integers must match the size of the constant, int, uint and
+	// floats are converted to it.
+	Specialization interface{}
+}
+
+func (c *ShaderStageConfig) setDefaults(module string) {
+	if c.Module == "" {
+		c.Module = module
+	}
+	if c.EntryPoint == "" {
+		c.EntryPoint = "main"
+	}
+}
+
+type specializationValue struct {
+	id    uint32
+	value reflect.Value
+}
+
+// specializationValues returns the constants set by a
+// ShaderStageConfig.Specialization, sorted by ID.
+func specializationValues(specialization interface{}) ([]specializationValue, error) {
+	if specialization == nil {
+		return nil, nil
+	}
+
+	v := reflect.ValueOf(specialization)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+
+	var values []specializationValue
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.Uint32:
+		iter := v.MapRange()
+		for iter.Next() {
+			values = append(values, specializationValue{uint32(iter.Key().Uint()), iter.Value()})
+		}
+	case v.Kind() == reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			tag, ok := field.Tag.Lookup("constant_id")
+			if !ok {
+				continue
+			}
+			id, err := strconv.ParseUint(tag, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: bad constant_id tag %q", field.Name, tag)
+			}
+			values = append(values, specializationValue{uint32(id), v.Field(i)})
+		}
+	default:
+		return nil, fmt.Errorf("specialization must be a map[uint32]interface{} or a struct, not %T", specialization)
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i].id < values[j].id })
+	for i := 1; i < len(values); i++ {
+		if values[i].id == values[i-1].id {
+			return nil, fmt.Errorf("specialization constant %d is set twice", values[i].id)
+		}
+	}
+
+	return values, nil
+}
+
+// specializationInfo serializes a ShaderStageConfig.Specialization into the
+// vk.SpecializationInfo of a stage, checking every constant against those
+// module declares. It returns nil when no constants are set.
+//
+// PData points at data, a copy in C memory, free it once the pipeline is
+// created.
+func specializationInfo(specialization interface{}, module *spirv.Module) (info []vk.SpecializationInfo, data *vkext.Data, err error) {
+	values, err := specializationValues(specialization)
+	if err != nil || len(values) == 0 {
+		return nil, nil, err
+	}
+
+	// Words keep every value 4-byte aligned and in host byte order.
+	var words []uint32
+	entries := make([]vk.SpecializationMapEntry, 0, len(values))
+	for _, v := range values {
+		constant, ok := module.SpecConstant(v.id)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: no specialization constant %d", ErrInvalidShader, v.id)
+		}
+
+		constantWords, err := specializationWords(constant, v.value)
+		if err != nil {
+			name := strconv.FormatUint(uint64(v.id), 10)
+			if constant.Name != "" {
+				name += " (" + constant.Name + ")"
+			}
+			return nil, nil, fmt.Errorf("specialization constant %s: %w", name, err)
+		}
+
+		entries = append(entries, vk.SpecializationMapEntry{
+			ConstantID: v.id,
+			Offset:     uint32(len(words) * 4),
+			Size:       uint(constant.Size),
+		})
+		words = append(words, constantWords...)
+	}
+
+	data = vkext.NewData(unsafe.Pointer(&words[0]), uintptr(len(words)*4))
+	return []vk.SpecializationInfo{{
+		MapEntryCount: uint32(len(entries)),
+		PMapEntries:   entries,
+		DataSize:      uint(len(words) * 4),
+		PData:         data.Ref(),
+	}}, data, nil
+}
+
+// specializationWords encodes value as the constant's type.
+func specializationWords(constant spirv.SpecConstant, value reflect.Value) ([]uint32, error) {
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return nil, fmt.Errorf("value is nil")
+	}
+	if constant.Size != 4 && constant.Size != 8 {
+		return nil, fmt.Errorf("unsupported constant size %d", constant.Size)
+	}
+
+	var bits uint64
+	switch value.Kind() {
+	case reflect.Bool:
+		if constant.Type != spirv.ScalarBool {
+			return nil, fmt.Errorf("constant is %s, not bool", constant.Type)
+		}
+		if value.Bool() {
+			bits = vk.True
+		}
+	case reflect.Float32, reflect.Float64:
+		if constant.Type != spirv.ScalarFloat {
+			return nil, fmt.Errorf("constant is %s, not float", constant.Type)
+		}
+		// Untyped float literals in a map are float64, let them set float
+		// constants too.
+		bits = math.Float64bits(value.Float())
+		if constant.Size == 4 {
+			bits = uint64(math.Float32bits(float32(value.Float())))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		err := checkInteger(constant, value)
+		if err != nil {
+			return nil, err
+		}
+		if constant.Size == 4 && (value.Int() < math.MinInt32 || value.Int() > math.MaxUint32) {
+			return nil, fmt.Errorf("%d doesn't fit in 32 bits", value.Int())
+		}
+		bits = uint64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		err := checkInteger(constant, value)
+		if err != nil {
+			return nil, err
+		}
+		if constant.Size == 4 && value.Uint() > math.MaxUint32 {
+			return nil, fmt.Errorf("%d doesn't fit in 32 bits", value.Uint())
+		}
+		bits = value.Uint()
+	default:
+		return nil, fmt.Errorf("unsupported value type %s", value.Type())
+	}
+
+	if constant.Size == 4 {
+		return []uint32{uint32(bits)}, nil
+	}
+	// Split in host byte order, the driver reads the words back as one
+	// 64-bit value.
+	words := *(*[2]uint32)(unsafe.Pointer(&bits))
+	return words[:], nil
+}
+
+// checkInteger rejects integer values for other constant types, and sized
+// integers that don't match the constant. int and uint adapt to it.
+func checkInteger(constant spirv.SpecConstant, value reflect.Value) error {
+	if constant.Type != spirv.ScalarInt && constant.Type != spirv.ScalarUint {
+		return fmt.Errorf("constant is %s, not an integer", constant.Type)
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Uint:
+		return nil
+	}
+	if value.Type().Size() != uintptr(constant.Size) {
+		return fmt.Errorf("constant is %d bytes, the value %d", constant.Size, value.Type().Size())
+	}
+	return nil
+}
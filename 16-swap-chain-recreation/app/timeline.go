@@ -0,0 +1,195 @@
+package app
+
+import (
+	"vulkan-tutorial-go/16-swap-chain-recreation/vkext"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// gpuTimeline tracks GPU progress as a monotonically increasing counter, the
+// same model as a timeline semaphore. Every queue submission made through it
+// signals the next value, and the CPU can wait for any value that has been
+// submitted.
+//
+// When the device has timeline semaphores, from Vulkan 1.2 or
+// VK_KHR_timeline_semaphore, the counter is one. Otherwise it is backed by
+// one fence per frame in flight. A slot is only reused after its fence has
+// been waited on, which means any value no longer held by a slot has
+// already completed.
+type gpuTimeline struct {
+	device vk.Device
+	// semaphore is the timeline semaphore, or vk.NullSemaphore when fences
+	// are used.
+	semaphore     vk.Semaphore
+	funcs         *vkext.Device
+	submitInfo    *vkext.TimelineSubmitInfo
+	fences        []vk.Fence
+	values        []uint64
+	submitted     uint64
+	completed     uint64
+	deletionQueue deletionQueue
+}
+
+// newGPUTimeline creates a timeline for slots frames in flight. It uses a
+// timeline semaphore when funcs is not nil, the timelineSemaphore feature
+// must then be enabled.
+func newGPUTimeline(device vk.Device, funcs *vkext.Device, slots int, leaks *leakTracker) (*gpuTimeline, error) {
+	t := &gpuTimeline{
+		device: device,
+		values: make([]uint64, slots),
+	}
+	if funcs != nil {
+		err := t.createSemaphore(funcs, leaks)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+	t.fences = make([]vk.Fence, slots)
+
+	fenceInfo := vk.FenceCreateInfo{
+		SType: vk.StructureTypeFenceCreateInfo,
+		PNext: nil,
+		Flags: vk.FenceCreateFlags(vk.FenceCreateSignaledBit),
+	}
+
+	for i := range t.fences {
+		var fence vk.Fence
+		err := vkError(vk.CreateFence(device, &fenceInfo, nil, &fence))
+		if err != nil {
+			t.destroy()
+			return nil, err
+		}
+
+		t.fences[i] = fence
+		t.deletionQueue.push(leaks.track("fence", fence, func() {
+			vk.DestroyFence(device, fence, nil)
+		}))
+	}
+
+	return t, nil
+}
+
+func (t *gpuTimeline) createSemaphore(funcs *vkext.Device, leaks *leakTracker) error {
+	typeInfo := vkext.NewTimelineSemaphoreCreateInfo(0)
+	defer typeInfo.Free()
+	semaphoreInfo := vk.SemaphoreCreateInfo{
+		SType: vk.StructureTypeSemaphoreCreateInfo,
+		PNext: typeInfo.Ref(),
+	}
+
+	var semaphore vk.Semaphore
+	err := vkError(vk.CreateSemaphore(t.device, &semaphoreInfo, nil, &semaphore))
+	if err != nil {
+		return err
+	}
+
+	t.semaphore = semaphore
+	t.funcs = funcs
+	t.submitInfo = vkext.NewTimelineSubmitInfo()
+	t.deletionQueue.push(t.submitInfo.Free)
+	t.deletionQueue.push(leaks.track("semaphore", semaphore, func() {
+		vk.DestroySemaphore(t.device, semaphore, nil)
+	}))
+
+	return nil
+}
+
+// wait blocks until the GPU has finished the submission that signalled value.
+func (t *gpuTimeline) wait(value uint64) error {
+	if value > t.submitted {
+		value = t.submitted
+	}
+	if value <= t.completed {
+		return nil
+	}
+
+	err := t.waitGPU(value)
+	if err != nil {
+		return err
+	}
+
+	for i, v := range t.values {
+		if v != 0 && v <= value {
+			t.values[i] = 0
+		}
+	}
+
+	if value > t.completed {
+		t.completed = value
+	}
+
+	return nil
+}
+
+func (t *gpuTimeline) waitGPU(value uint64) error {
+	if t.semaphore != vk.NullSemaphore {
+		return vkError(t.funcs.WaitSemaphore(t.semaphore, value, vk.MaxUint64))
+	}
+
+	var fences []vk.Fence
+	for i, v := range t.values {
+		if v != 0 && v <= value {
+			fences = append(fences, t.fences[i])
+		}
+	}
+	if len(fences) == 0 {
+		return nil
+	}
+
+	return vkError(vk.WaitForFences(t.device, uint32(len(fences)), fences, vk.True, vk.MaxUint64))
+}
+
+// waitSlot blocks until the previous submission that used slot has finished.
+func (t *gpuTimeline) waitSlot(slot int) error {
+	return t.wait(t.values[slot])
+}
+
+// prepareSubmit makes submitInfo, the next queue submission from slot,
+// signal the timeline and returns the fence to pass to vkQueueSubmit.
+// waitSlot must have been called first. The timeline semaphore is added to
+// the signalled semaphores and the values to PNext, which must be nil.
+func (t *gpuTimeline) prepareSubmit(slot int, submitInfo *vk.SubmitInfo) (vk.Fence, error) {
+	if t.semaphore != vk.NullSemaphore {
+		signals := append(submitInfo.PSignalSemaphores[:submitInfo.SignalSemaphoreCount:submitInfo.SignalSemaphoreCount], t.semaphore)
+		values := make([]uint64, len(signals))
+		values[len(values)-1] = t.submitted + 1
+		t.submitInfo.SetSignalValues(values)
+
+		submitInfo.SignalSemaphoreCount = uint32(len(signals))
+		submitInfo.PSignalSemaphores = signals
+		submitInfo.PNext = t.submitInfo.Ref()
+		return vk.NullFence, nil
+	}
+
+	fence := t.fences[slot]
+	err := vkError(vk.ResetFences(t.device, 1, []vk.Fence{fence}))
+	if err != nil {
+		return vk.NullFence, err
+	}
+
+	return fence, nil
+}
+
+// signal records that the submission prepared for slot was queued and
+// returns the value it will signal.
+func (t *gpuTimeline) signal(slot int) uint64 {
+	t.submitted++
+	t.values[slot] = t.submitted
+	return t.submitted
+}
+
+func (t *gpuTimeline) destroy() {
+	t.deletionQueue.flush()
+}
+
+// SubmittedValue is the timeline value of the most recent frame submission.
+func (a *app) SubmittedValue() uint64 {
+	return a.timeline.submitted
+}
+
+// WaitGPU blocks until the GPU has finished the frame submission that
+// returned value from SubmittedValue.
+func (a *app) WaitGPU(value uint64) error {
+	return a.timeline.wait(value)
+}
@@ -1,7 +1,6 @@
 package app
 
 import (
-	"fmt"
 	"log"
 	"strings"
 	"unicode"
@@ -19,7 +18,7 @@ func (q *queueFamilyIndices) isComplete() bool {
 	return q.graphicsFamily != nil && q.presentFamily != nil
 }
 
-func findQueueFamilies(device vk.PhysicalDevice, surface vk.Surface) queueFamilyIndices {
+func findQueueFamilies(device vk.PhysicalDevice, surface vk.Surface) (queueFamilyIndices, error) {
 	var indices queueFamilyIndices
 
 	var propCount uint32
@@ -39,7 +38,10 @@ func findQueueFamilies(device vk.PhysicalDevice, surface vk.Surface) queueFamily
 		}
 
 		var isSupported vk.Bool32
-		vk.GetPhysicalDeviceSurfaceSupport(device, uint32(i), surface, &isSupported)
+		err := vkErrorf(vk.GetPhysicalDeviceSurfaceSupport(device, uint32(i), surface, &isSupported), "failed to query surface support")
+		if err != nil {
+			return queueFamilyIndices{}, err
+		}
 		if isSupported == vk.True {
 			tmp := uint32(i)
 			indices.presentFamily = &tmp
@@ -50,7 +52,32 @@ func findQueueFamilies(device vk.PhysicalDevice, surface vk.Surface) queueFamily
 		}
 	}
 
-	return indices
+	return indices, nil
+}
+
+// maxEnumerateAttempts bounds how often enumerate retries a list that keeps
+// growing between the count query and the fill.
+const maxEnumerateAttempts = 8
+
+// enumerate runs fn, a two-call count-then-fill enumeration, until it stops
+// returning vk.Incomplete. Incomplete means the list grew between the two
+// calls and the filled slice is missing entries.
+func enumerate(fn func() vk.Result) error {
+	res := vk.Incomplete
+	for i := 0; i < maxEnumerateAttempts && res == vk.Incomplete; i++ {
+		res = fn()
+	}
+
+	return vkError(res)
+}
+
+// nullTerminate appends the NUL byte the bindings expect on strings passed to
+// Vulkan, unless s already ends with one.
+func nullTerminate(s string) string {
+	if strings.HasSuffix(s, "\x00") {
+		return s
+	}
+	return s + "\x00"
 }
 
 func defaultDebugCreateInfo() vk.DebugReportCallbackCreateInfo {
@@ -71,11 +98,86 @@ func defaultDebugCreateInfo() vk.DebugReportCallbackCreateInfo {
 	}
 }
 
-func checkExtensionSupport(requiredExtensions []string) error {
-	var extensionCount uint32
-	vk.EnumerateInstanceExtensionProperties("", &extensionCount, nil)
-	extensionProperties := make([]vk.ExtensionProperties, extensionCount)
-	vk.EnumerateInstanceExtensionProperties("", &extensionCount, extensionProperties)
+// extensionName strips the NUL terminator and any other non-printable
+// characters from a layer or extension name.
+func extensionName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsPrint(r) {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// negotiateNames picks the names to enable out of required and optional.
+// Optional names are enabled only when supported, every unsupported required
+// name is returned in missing. Returned names are not NUL terminated.
+func negotiateNames(supported map[string]bool, required, optional []string) (enabled []string, missing []string) {
+	seen := make(map[string]bool)
+	for _, name := range required {
+		name = extensionName(name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if !supported[name] {
+			missing = append(missing, name)
+			continue
+		}
+		enabled = append(enabled, name)
+	}
+
+	for _, name := range optional {
+		name = extensionName(name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if supported[name] {
+			enabled = append(enabled, name)
+		}
+	}
+
+	return enabled, missing
+}
+
+func containsName(names []string, name string) bool {
+	name = extensionName(name)
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// nullTerminateAll returns names NUL terminated, ready to be passed to Vulkan.
+func nullTerminateAll(names []string) []string {
+	terminated := make([]string, len(names))
+	for i, name := range names {
+		terminated[i] = nullTerminate(name)
+	}
+	return terminated
+}
+
+func supportedInstanceExtensions() (map[string]bool, error) {
+	var extensionProperties []vk.ExtensionProperties
+	err := enumerate(func() vk.Result {
+		var extensionCount uint32
+		res := vk.EnumerateInstanceExtensionProperties("", &extensionCount, nil)
+		if res != vk.Success {
+			return res
+		}
+		extensionProperties = make([]vk.ExtensionProperties, extensionCount)
+		res = vk.EnumerateInstanceExtensionProperties("", &extensionCount, extensionProperties)
+		extensionProperties = extensionProperties[:extensionCount]
+		return res
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	supportedExtensions := make(map[string]bool)
 	for _, extensionProperty := range extensionProperties {
@@ -84,26 +186,25 @@ func checkExtensionSupport(requiredExtensions []string) error {
 		extensionProperty.Free()
 	}
 
-	for _, requiredExtension := range requiredExtensions {
-		requiredExtension = strings.Map(func(r rune) rune {
-			if unicode.IsPrint(r) {
-				return r
-			}
-			return -1
-		}, requiredExtension)
-		if !supportedExtensions[requiredExtension] {
-			return fmt.Errorf(requiredExtension + " - is not a supported extension")
-		}
-	}
-
-	return nil
+	return supportedExtensions, nil
 }
 
-func checkValidationLayerSupport(requiredLayers []string) error {
-	var layerCount uint32
-	vk.EnumerateInstanceLayerProperties(&layerCount, nil)
-	layerProperties := make([]vk.LayerProperties, layerCount)
-	vk.EnumerateInstanceLayerProperties(&layerCount, layerProperties)
+func supportedLayers() (map[string]bool, error) {
+	var layerProperties []vk.LayerProperties
+	err := enumerate(func() vk.Result {
+		var layerCount uint32
+		res := vk.EnumerateInstanceLayerProperties(&layerCount, nil)
+		if res != vk.Success {
+			return res
+		}
+		layerProperties = make([]vk.LayerProperties, layerCount)
+		res = vk.EnumerateInstanceLayerProperties(&layerCount, layerProperties)
+		layerProperties = layerProperties[:layerCount]
+		return res
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	supportedLayers := make(map[string]bool)
 	for _, layerProperty := range layerProperties {
@@ -112,26 +213,25 @@ func checkValidationLayerSupport(requiredLayers []string) error {
 		layerProperty.Free()
 	}
 
-	for _, requiredLayer := range requiredLayers {
-		requiredLayer = strings.Map(func(r rune) rune {
-			if unicode.IsPrint(r) {
-				return r
-			}
-			return -1
-		}, requiredLayer)
-		if !supportedLayers[requiredLayer] {
-			return fmt.Errorf(requiredLayer + " - is not a supported layer")
-		}
-	}
-
-	return nil
+	return supportedLayers, nil
 }
 
-func checkDeviceExtensionsSupport(device vk.PhysicalDevice, requiredDeviceExtensions []string) bool {
-	var count uint32
-	vk.EnumerateDeviceExtensionProperties(device, "", &count, nil)
-	extensionProperties := make([]vk.ExtensionProperties, count)
-	vk.EnumerateDeviceExtensionProperties(device, "", &count, extensionProperties)
+func supportedDeviceExtensions(device vk.PhysicalDevice) (map[string]bool, error) {
+	var extensionProperties []vk.ExtensionProperties
+	err := enumerate(func() vk.Result {
+		var count uint32
+		res := vk.EnumerateDeviceExtensionProperties(device, "", &count, nil)
+		if res != vk.Success {
+			return res
+		}
+		extensionProperties = make([]vk.ExtensionProperties, count)
+		res = vk.EnumerateDeviceExtensionProperties(device, "", &count, extensionProperties)
+		extensionProperties = extensionProperties[:count]
+		return res
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	supportedExtensions := make(map[string]bool, len(extensionProperties))
 	for _, ep := range extensionProperties {
@@ -140,20 +240,19 @@ func checkDeviceExtensionsSupport(device vk.PhysicalDevice, requiredDeviceExtens
 		ep.Free()
 	}
 
-	for _, requiredExtension := range requiredDeviceExtensions {
-		requiredExtension = strings.Map(func(r rune) rune {
-			if unicode.IsPrint(r) {
-				return r
-			}
-			return -1
-		}, requiredExtension)
+	return supportedExtensions, nil
+}
 
-		if !supportedExtensions[requiredExtension] {
-			return false
-		}
+// missingDeviceExtensions returns every name in requiredDeviceExtensions that
+// device does not support.
+func missingDeviceExtensions(device vk.PhysicalDevice, requiredDeviceExtensions []string) ([]string, error) {
+	supported, err := supportedDeviceExtensions(device)
+	if err != nil {
+		return nil, err
 	}
 
-	return true
+	_, missing := negotiateNames(supported, requiredDeviceExtensions, nil)
+	return missing, nil
 }
 
 type swapChainSupportDetails struct {
@@ -162,26 +261,43 @@ type swapChainSupportDetails struct {
 	presentationModes []vk.PresentMode
 }
 
-func querySwapChainSupport(device vk.PhysicalDevice, surface vk.Surface) swapChainSupportDetails {
+func querySwapChainSupport(device vk.PhysicalDevice, surface vk.Surface) (swapChainSupportDetails, error) {
 	var details swapChainSupportDetails
 
-	vk.GetPhysicalDeviceSurfaceCapabilities(device, surface, &details.capabilities)
+	err := vkErrorf(vk.GetPhysicalDeviceSurfaceCapabilities(device, surface, &details.capabilities), "failed to query surface capabilities")
+	if err != nil {
+		return swapChainSupportDetails{}, err
+	}
 
-	var formatCount uint32
-	vk.GetPhysicalDeviceSurfaceFormats(device, surface, &formatCount, nil)
-	if formatCount != 0 {
+	err = enumerate(func() vk.Result {
+		var formatCount uint32
+		res := vk.GetPhysicalDeviceSurfaceFormats(device, surface, &formatCount, nil)
+		if res != vk.Success || formatCount == 0 {
+			return res
+		}
 		surfaceFormats := make([]vk.SurfaceFormat, formatCount)
-		vk.GetPhysicalDeviceSurfaceFormats(device, surface, &formatCount, surfaceFormats)
-		details.surfaceFormats = surfaceFormats
+		res = vk.GetPhysicalDeviceSurfaceFormats(device, surface, &formatCount, surfaceFormats)
+		details.surfaceFormats = surfaceFormats[:formatCount]
+		return res
+	})
+	if err != nil {
+		return swapChainSupportDetails{}, err
 	}
 
-	var modeCount uint32
-	vk.GetPhysicalDeviceSurfacePresentModes(device, surface, &modeCount, nil)
-	if modeCount != 0 {
+	err = enumerate(func() vk.Result {
+		var modeCount uint32
+		res := vk.GetPhysicalDeviceSurfacePresentModes(device, surface, &modeCount, nil)
+		if res != vk.Success || modeCount == 0 {
+			return res
+		}
 		presentationModes := make([]vk.PresentMode, modeCount)
-		vk.GetPhysicalDeviceSurfacePresentModes(device, surface, &modeCount, presentationModes)
-		details.presentationModes = presentationModes
+		res = vk.GetPhysicalDeviceSurfacePresentModes(device, surface, &modeCount, presentationModes)
+		details.presentationModes = presentationModes[:modeCount]
+		return res
+	})
+	if err != nil {
+		return swapChainSupportDetails{}, err
 	}
 
-	return details
+	return details, nil
 }
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"vulkan-tutorial-go/16-swap-chain-recreation/vkext"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+const (
+	// fullScreenExclusiveExtension lets a fullscreen swapchain take the
+	// display over from the compositor. Only Windows drivers offer it.
+	fullScreenExclusiveExtension = "VK_EXT_full_screen_exclusive"
+	// fullScreenExclusive depends on it.
+	surfaceCapabilities2Extension = "VK_KHR_get_surface_capabilities2"
+)
+
+type WindowMode int
+
+const (
+	WindowModeWindowed WindowMode = iota
+	// WindowModeFullscreen switches the monitor to WindowConfig.VideoMode.
+	// When the device has VK_EXT_full_screen_exclusive the swapchain may also
+	// take exclusive ownership of the display.
+	WindowModeFullscreen
+	// WindowModeBorderless covers the monitor with an undecorated window at
+	// the monitor's current video mode.
+	WindowModeBorderless
+)
+
+func (m WindowMode) String() string {
+	switch m {
+	case WindowModeWindowed:
+		return "windowed"
+	case WindowModeFullscreen:
+		return "fullscreen"
+	case WindowModeBorderless:
+		return "borderless"
+	default:
+		return fmt.Sprintf("WindowMode(%d)", int(m))
+	}
+}
+
+type VideoMode struct {
+	Width       int
+	Height      int
+	RefreshRate int
+}
+
+type WindowConfig struct {
+	// Width and Height default to 800x600 when zero.
+	Width  int
+	Height int
+	// Title defaults to AppConfig.ApplicationName, or "Vulkan" when that is
+	// empty too.
+	Title string
+	// NotResizable fixes the window size, windows are resizable by default.
+	NotResizable bool
+	Mode         WindowMode
+	// Monitor is the index into glfw.GetMonitors() used by the fullscreen and
+	// borderless modes. 0 is the primary monitor.
+	Monitor int
+	// VideoMode is the fullscreen video mode. When nil the monitor's current
+	// mode is used.
+	VideoMode *VideoMode
+}
+
+// windowedRect remembers where the window was before it left windowed mode so
+// it can be restored.
+type windowedRect struct {
+	x, y          int
+	width, height int
+}
+
+func getMonitor(index int) (*glfw.Monitor, error) {
+	monitors := glfw.GetMonitors()
+	if index < 0 || index >= len(monitors) {
+		return nil, fmt.Errorf("monitor %d does not exist, %d monitors connected", index, len(monitors))
+	}
+
+	return monitors[index], nil
+}
+
+// SetWindowMode switches the window between windowed, fullscreen and
+// borderless modes. The swapchain is recreated on the next frame.
+func (a *app) SetWindowMode(mode WindowMode) error {
+	if mode == a.windowMode {
+		return nil
+	}
+
+	if a.windowMode == WindowModeWindowed {
+		x, y := a.window.GetPos()
+		w, h := a.window.GetSize()
+		a.windowedRect = windowedRect{x: x, y: y, width: w, height: h}
+	}
+
+	err := a.applyWindowMode(mode)
+	if err != nil {
+		return err
+	}
+
+	a.frameBufferResized = true
+
+	return nil
+}
+
+// ToggleFullscreen switches between windowed and fullscreen mode.
+func (a *app) ToggleFullscreen() error {
+	if a.windowMode == WindowModeWindowed {
+		return a.SetWindowMode(WindowModeFullscreen)
+	}
+
+	return a.SetWindowMode(WindowModeWindowed)
+}
+
+func (a *app) applyWindowMode(mode WindowMode) error {
+	switch mode {
+	case WindowModeWindowed:
+		a.window.SetAttrib(glfw.Decorated, glfw.True)
+		r := a.windowedRect
+		a.window.SetMonitor(nil, r.x, r.y, r.width, r.height, glfw.DontCare)
+	case WindowModeFullscreen:
+		monitor, err := getMonitor(a.config.Window.Monitor)
+		if err != nil {
+			return err
+		}
+
+		vidMode := monitor.GetVideoMode()
+		w, h, refreshRate := vidMode.Width, vidMode.Height, vidMode.RefreshRate
+		if vm := a.config.Window.VideoMode; vm != nil {
+			w, h, refreshRate = vm.Width, vm.Height, vm.RefreshRate
+		}
+		a.window.SetMonitor(monitor, 0, 0, w, h, refreshRate)
+	case WindowModeBorderless:
+		monitor, err := getMonitor(a.config.Window.Monitor)
+		if err != nil {
+			return err
+		}
+
+		vidMode := monitor.GetVideoMode()
+		x, y := monitor.GetPos()
+		a.window.SetAttrib(glfw.Decorated, glfw.False)
+		a.window.SetMonitor(nil, x, y, vidMode.Width, vidMode.Height, glfw.DontCare)
+	default:
+		return fmt.Errorf("unknown window mode %s", mode)
+	}
+
+	a.windowMode = mode
+
+	return nil
+}
+
+// fullScreenExclusiveInstanceExtensions returns the optional instance
+// extensions VK_EXT_full_screen_exclusive needs.
+func fullScreenExclusiveInstanceExtensions() []string {
+	return []string{surfaceCapabilities2Extension}
+}
+
+// fullScreenExclusiveDeviceExtensions returns VK_EXT_full_screen_exclusive
+// when the device advertises it, in supported, and the instance has the
+// extensions it depends on.
+func (a *app) fullScreenExclusiveDeviceExtensions(supported map[string]bool) []string {
+	if !supported[fullScreenExclusiveExtension] || !a.HasInstanceExtension(surfaceCapabilities2Extension) {
+		return nil
+	}
+	if a.apiVersion < vk.MakeVersion(1, 1, 0) && !a.HasInstanceExtension(physicalDeviceProperties2Extension) {
+		return nil
+	}
+	return []string{fullScreenExclusiveExtension}
+}
+
+// fullScreenExclusive returns whether the swapchain may use exclusive
+// fullscreen. It is allowed in fullscreen mode, and disallowed otherwise so a
+// borderless window stays composited.
+func (a *app) fullScreenExclusive() vkext.FullScreenExclusive {
+	if a.windowMode == WindowModeFullscreen {
+		return vkext.FullScreenExclusiveAllowed
+	}
+	return vkext.FullScreenExclusiveDisallowed
+}
+
+// logFullScreenExclusive reports whether fullscreen windows can use
+// VK_EXT_full_screen_exclusive.
+func (a *app) logFullScreenExclusive() {
+	if a.HasDeviceExtension(fullScreenExclusiveExtension) {
+		log.Printf("using %s, fullscreen windows may take exclusive ownership of the display", fullScreenExclusiveExtension)
+	}
+}
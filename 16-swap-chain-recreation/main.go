@@ -3,25 +3,67 @@ package main
 import (
 	"log"
 	"os"
+	"path/filepath"
+	"time"
 	"vulkan-tutorial-go/16-swap-chain-recreation/app"
+	"vulkan-tutorial-go/16-swap-chain-recreation/shaders"
+
+	vk "github.com/vulkan-go/vulkan"
 )
 
 func main() {
 	profile := os.Getenv("PROFILE")
 
 	var enableValidationLayers bool
+	var memoryReportInterval time.Duration
+	var compiler *shaders.Compiler
 	if profile != "prod" {
 		enableValidationLayers = true
+		memoryReportInterval = 10 * time.Second
+
+		var err error
+		compiler, err = shaders.NewCompiler(shaders.Options{})
+		if err != nil {
+			log.Printf("%s, using precompiled shaders", err)
+		}
+	}
+
+	// SHADER_PATH lists directories with shader sources or SPIR-V that
+	// take precedence over the embedded shaders.
+	shaderSearchPaths := filepath.SplitList(os.Getenv("SHADER_PATH"))
+	if len(shaderSearchPaths) == 0 && profile != "prod" {
+		shaderSearchPaths = chapterShaderDirs()
 	}
 
-	a := app.New(app.AppConfig{EnableValidationLayers: enableValidationLayers, ValidationLayers: []string{
+	a := app.New(app.AppConfig{APIVersion: vk.MakeVersion(1, 3, 0), EnableValidationLayers: enableValidationLayers, TrackLeaks: enableValidationLayers, ValidationLayers: []string{
 		"VK_LAYER_KHRONOS_validation\x00",
 	}, RequiredDeviceExtensions: []string{
 		"VK_KHR_swapchain\x00",
-	}})
+	}, OptionalDeviceExtensions: []string{
+		"VK_EXT_memory_budget\x00",
+	}, RequiredFeatures: vk.PhysicalDeviceFeatures{
+		SamplerAnisotropy: vk.True,
+		FillModeNonSolid:  vk.True,
+		WideLines:         vk.True,
+	}, MemoryReportInterval: memoryReportInterval,
+		ShaderSearchPaths: shaderSearchPaths,
+		ShaderCompiler:    compiler,
+		HotReloadShaders:  profile != "prod",
+	})
 
 	err := a.Run()
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// chapterShaderDirs returns the chapter's shaders directory when run from the
+// chapter or the repository root, so edits to its sources are picked up.
+func chapterShaderDirs() []string {
+	for _, dir := range []string{"shaders", filepath.Join("16-swap-chain-recreation", "shaders")} {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return []string{dir}
+		}
+	}
+	return nil
+}
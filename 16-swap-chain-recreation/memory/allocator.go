@@ -0,0 +1,395 @@
+// Package memory sub-allocates Vulkan device memory. Resources are placed in
+// large vk.DeviceMemory blocks instead of getting an allocation each, which
+// keeps the app far below maxMemoryAllocationCount.
+//
+// Errors from Vulkan calls wrap vk.Error(result), test for e.g.
+// vk.Error(vk.ErrorOutOfDeviceMemory) with errors.Is.
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// DefaultBlockSize is the size of the blocks allocations are carved from
+// unless Config.BlockSize says otherwise.
+const DefaultBlockSize vk.DeviceSize = 64 << 20
+
+var (
+	ErrNoMemoryType       = errors.New("no memory type satisfies the requirements")
+	ErrTooManyAllocations = errors.New("maxMemoryAllocationCount reached")
+	ErrNotHostVisible     = errors.New("allocation is not host visible")
+)
+
+// Kind is the kind of resource an allocation is bound to. Linear resources
+// (buffers, linear images) and non-linear ones (optimal images) closer than
+// bufferImageGranularity are kept on separate pages.
+type Kind int
+
+const (
+	// KindUnknown is kept apart from every other kind.
+	KindUnknown Kind = iota
+	KindBuffer
+	KindImageLinear
+	KindImageOptimal
+)
+
+func (k Kind) linear() bool {
+	return k == KindBuffer || k == KindImageLinear
+}
+
+// Usage describes how the CPU and GPU access an allocation, it picks the
+// memory type.
+type Usage int
+
+const (
+	// UsageGPUOnly prefers device local memory the CPU can't map.
+	UsageGPUOnly Usage = iota
+	// UsageCPUToGPU is host visible and coherent, device local when there
+	// is such a type. For uploads and per-frame uniforms.
+	UsageCPUToGPU
+	// UsageGPUToCPU is host visible and coherent, cached when there is such
+	// a type. For readbacks.
+	UsageGPUToCPU
+)
+
+func (u Usage) flags() (required, preferred vk.MemoryPropertyFlags) {
+	switch u {
+	case UsageCPUToGPU:
+		return vk.MemoryPropertyFlags(vk.MemoryPropertyHostVisibleBit | vk.MemoryPropertyHostCoherentBit),
+			vk.MemoryPropertyFlags(vk.MemoryPropertyDeviceLocalBit)
+	case UsageGPUToCPU:
+		return vk.MemoryPropertyFlags(vk.MemoryPropertyHostVisibleBit | vk.MemoryPropertyHostCoherentBit),
+			vk.MemoryPropertyFlags(vk.MemoryPropertyHostCachedBit)
+	default:
+		return 0, vk.MemoryPropertyFlags(vk.MemoryPropertyDeviceLocalBit)
+	}
+}
+
+type Config struct {
+	// BlockSize is the size of the vk.DeviceMemory blocks allocations are
+	// carved from. Requests larger than half a block get a block of their
+	// own. Defaults to DefaultBlockSize.
+	BlockSize vk.DeviceSize
+	// QueryBudget returns the budget and usage of every heap as
+	// VK_EXT_memory_budget reports them, or false when it can't. Without it
+	// Allocator.Budget estimates them.
+	QueryBudget func() (budget, usage []vk.DeviceSize, ok bool)
+}
+
+// Allocation is a range of a vk.DeviceMemory block.
+type Allocation struct {
+	Offset vk.DeviceSize
+	Size   vk.DeviceSize
+	block  *block
+}
+
+// Memory is the vk.DeviceMemory to bind at Offset.
+func (a *Allocation) Memory() vk.DeviceMemory {
+	return a.block.memory
+}
+
+// MemoryType is the index of the memory type the allocation lives in.
+func (a *Allocation) MemoryType() uint32 {
+	return a.block.memoryType
+}
+
+// Allocator hands out Allocations from blocks of device memory. It is safe
+// for concurrent use.
+type Allocator struct {
+	mu                 sync.Mutex
+	device             vk.Device
+	memoryTypes        []vk.MemoryType
+	memoryHeaps        []vk.MemoryHeap
+	granularity        vk.DeviceSize
+	maxAllocationCount uint32
+	blockSize          vk.DeviceSize
+	queryBudget        func() (budget, usage []vk.DeviceSize, ok bool)
+	// blocks holds the blocks of each memory type.
+	blocks            [][]*block
+	deviceAllocations uint32
+}
+
+func New(device vk.Device, physicalDevice vk.PhysicalDevice, config Config) *Allocator {
+	if config.BlockSize == 0 {
+		config.BlockSize = DefaultBlockSize
+	}
+
+	var memoryProperties vk.PhysicalDeviceMemoryProperties
+	vk.GetPhysicalDeviceMemoryProperties(physicalDevice, &memoryProperties)
+	memoryProperties.Deref()
+	memoryProperties.Free()
+
+	var properties vk.PhysicalDeviceProperties
+	vk.GetPhysicalDeviceProperties(physicalDevice, &properties)
+	properties.Deref()
+	// Limits points into properties, it must be read before the free.
+	properties.Limits.Deref()
+	properties.Free()
+
+	a := &Allocator{
+		device:             device,
+		memoryTypes:        make([]vk.MemoryType, memoryProperties.MemoryTypeCount),
+		memoryHeaps:        make([]vk.MemoryHeap, memoryProperties.MemoryHeapCount),
+		granularity:        properties.Limits.BufferImageGranularity,
+		maxAllocationCount: properties.Limits.MaxMemoryAllocationCount,
+		blockSize:          config.BlockSize,
+		queryBudget:        config.QueryBudget,
+		blocks:             make([][]*block, memoryProperties.MemoryTypeCount),
+	}
+	for i := range a.memoryTypes {
+		memoryType := memoryProperties.MemoryTypes[i]
+		memoryType.Deref()
+		a.memoryTypes[i] = memoryType
+	}
+	for i := range a.memoryHeaps {
+		memoryHeap := memoryProperties.MemoryHeaps[i]
+		memoryHeap.Deref()
+		a.memoryHeaps[i] = memoryHeap
+	}
+
+	return a
+}
+
+// MemoryHeaps returns the heaps of the physical device.
+func (a *Allocator) MemoryHeaps() []vk.MemoryHeap {
+	return a.memoryHeaps
+}
+
+// MemoryTypes returns the memory types of the physical device.
+func (a *Allocator) MemoryTypes() []vk.MemoryType {
+	return a.memoryTypes
+}
+
+// memoryTypeCandidates returns the memory types allowed by typeBits that have
+// the required flags, those with the preferred flags first.
+func (a *Allocator) memoryTypeCandidates(typeBits uint32, usage Usage) []uint32 {
+	required, preferred := usage.flags()
+
+	var best, rest []uint32
+	for i, memoryType := range a.memoryTypes {
+		if typeBits&(1<<uint(i)) == 0 || memoryType.PropertyFlags&required != required {
+			continue
+		}
+		if memoryType.PropertyFlags&preferred == preferred {
+			best = append(best, uint32(i))
+		} else {
+			rest = append(rest, uint32(i))
+		}
+	}
+
+	return append(best, rest...)
+}
+
+// Allocate finds room for a resource with the given requirements. Memory
+// types are tried in order of preference, moving on when one runs out.
+func (a *Allocator) Allocate(requirements vk.MemoryRequirements, usage Usage, kind Kind) (*Allocation, error) {
+	candidates := a.memoryTypeCandidates(requirements.MemoryTypeBits, usage)
+	if len(candidates) == 0 {
+		return nil, ErrNoMemoryType
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var err error
+	for _, memoryType := range candidates {
+		var allocation *Allocation
+		allocation, err = a.allocate(memoryType, requirements, kind)
+		if err == nil {
+			return allocation, nil
+		}
+		if !errors.Is(err, vk.Error(vk.ErrorOutOfDeviceMemory)) && !errors.Is(err, vk.Error(vk.ErrorOutOfHostMemory)) {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+func (a *Allocator) allocate(memoryType uint32, requirements vk.MemoryRequirements, kind Kind) (*Allocation, error) {
+	size, alignment := requirements.Size, requirements.Alignment
+
+	if size > a.blockSize/2 {
+		b, err := a.newBlock(memoryType, size, true)
+		if err != nil {
+			return nil, err
+		}
+		offset, _ := b.allocate(size, alignment, a.granularity, kind)
+		return &Allocation{Offset: offset, Size: size, block: b}, nil
+	}
+
+	for _, b := range a.blocks[memoryType] {
+		if b.dedicated || b.size-b.used < size {
+			continue
+		}
+		if offset, ok := b.allocate(size, alignment, a.granularity, kind); ok {
+			return &Allocation{Offset: offset, Size: size, block: b}, nil
+		}
+	}
+
+	b, err := a.newBlock(memoryType, a.blockSize, false)
+	if err != nil {
+		return nil, err
+	}
+	offset, _ := b.allocate(size, alignment, a.granularity, kind)
+
+	return &Allocation{Offset: offset, Size: size, block: b}, nil
+}
+
+func (a *Allocator) newBlock(memoryType uint32, size vk.DeviceSize, dedicated bool) (*block, error) {
+	if a.maxAllocationCount > 0 && a.deviceAllocations >= a.maxAllocationCount {
+		return nil, ErrTooManyAllocations
+	}
+
+	allocateInfo := vk.MemoryAllocateInfo{
+		SType:           vk.StructureTypeMemoryAllocateInfo,
+		AllocationSize:  size,
+		MemoryTypeIndex: memoryType,
+	}
+
+	var memory vk.DeviceMemory
+	res := vk.AllocateMemory(a.device, &allocateInfo, nil, &memory)
+	if res != vk.Success {
+		return nil, fmt.Errorf("failed to allocate %d bytes of memory type %d: %w", size, memoryType, vk.Error(res))
+	}
+	a.deviceAllocations++
+
+	b := newBlock(memory, memoryType, size, dedicated)
+	a.blocks[memoryType] = append(a.blocks[memoryType], b)
+
+	return b, nil
+}
+
+// AllocateBuffer allocates memory for buffer and binds it.
+func (a *Allocator) AllocateBuffer(buffer vk.Buffer, usage Usage) (*Allocation, error) {
+	var requirements vk.MemoryRequirements
+	vk.GetBufferMemoryRequirements(a.device, buffer, &requirements)
+	requirements.Deref()
+	requirements.Free()
+
+	allocation, err := a.Allocate(requirements, usage, KindBuffer)
+	if err != nil {
+		return nil, err
+	}
+
+	res := vk.BindBufferMemory(a.device, buffer, allocation.Memory(), allocation.Offset)
+	if res != vk.Success {
+		a.Free(allocation)
+		return nil, fmt.Errorf("failed to bind buffer memory: %w", vk.Error(res))
+	}
+
+	return allocation, nil
+}
+
+// AllocateImage allocates memory for image, created with tiling, and binds
+// it.
+func (a *Allocator) AllocateImage(image vk.Image, tiling vk.ImageTiling, usage Usage) (*Allocation, error) {
+	var requirements vk.MemoryRequirements
+	vk.GetImageMemoryRequirements(a.device, image, &requirements)
+	requirements.Deref()
+	requirements.Free()
+
+	kind := KindImageOptimal
+	if tiling == vk.ImageTilingLinear {
+		kind = KindImageLinear
+	}
+
+	allocation, err := a.Allocate(requirements, usage, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	res := vk.BindImageMemory(a.device, image, allocation.Memory(), allocation.Offset)
+	if res != vk.Success {
+		a.Free(allocation)
+		return nil, fmt.Errorf("failed to bind image memory: %w", vk.Error(res))
+	}
+
+	return allocation, nil
+}
+
+// Free returns allocation to its block. Empty blocks are released, except
+// for the last regular block of each memory type.
+func (a *Allocator) Free(allocation *Allocation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	b := allocation.block
+	b.free(allocation.Offset)
+	allocation.block = nil
+
+	if b.count > 0 {
+		return
+	}
+
+	blocks := a.blocks[b.memoryType]
+	if !b.dedicated {
+		regular := 0
+		for _, other := range blocks {
+			if !other.dedicated {
+				regular++
+			}
+		}
+		if regular <= 1 {
+			return
+		}
+	}
+
+	for i, other := range blocks {
+		if other == b {
+			a.blocks[b.memoryType] = append(blocks[:i], blocks[i+1:]...)
+			break
+		}
+	}
+	a.freeBlock(b)
+}
+
+func (a *Allocator) freeBlock(b *block) {
+	if b.mapped != nil {
+		vk.UnmapMemory(a.device, b.memory)
+	}
+	vk.FreeMemory(a.device, b.memory, nil)
+	a.deviceAllocations--
+}
+
+// Map returns a pointer to the start of allocation. The block stays mapped
+// until it is released, so the pointer is valid until allocation is freed.
+func (a *Allocator) Map(allocation *Allocation) (unsafe.Pointer, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	b := allocation.block
+	hostVisible := vk.MemoryPropertyFlags(vk.MemoryPropertyHostVisibleBit)
+	if a.memoryTypes[b.memoryType].PropertyFlags&hostVisible == 0 {
+		return nil, ErrNotHostVisible
+	}
+
+	if b.mapped == nil {
+		var data unsafe.Pointer
+		res := vk.MapMemory(a.device, b.memory, 0, vk.DeviceSize(vk.WholeSize), 0, &data)
+		if res != vk.Success {
+			return nil, fmt.Errorf("failed to map memory: %w", vk.Error(res))
+		}
+		b.mapped = data
+	}
+
+	return unsafe.Pointer(uintptr(b.mapped) + uintptr(allocation.Offset)), nil
+}
+
+// Destroy frees every block, including those still holding allocations.
+func (a *Allocator) Destroy() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for memoryType, blocks := range a.blocks {
+		for _, b := range blocks {
+			a.freeBlock(b)
+		}
+		a.blocks[memoryType] = nil
+	}
+}
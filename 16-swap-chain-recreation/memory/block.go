@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// region is a contiguous range of a block, either free or holding one
+// allocation.
+type region struct {
+	offset vk.DeviceSize
+	size   vk.DeviceSize
+	free   bool
+	kind   Kind
+}
+
+func (r region) end() vk.DeviceSize {
+	return r.offset + r.size
+}
+
+// block is one vk.DeviceMemory object. Its regions are kept sorted by offset
+// and cover the whole block, adjacent free regions are always merged.
+type block struct {
+	memory     vk.DeviceMemory
+	memoryType uint32
+	size       vk.DeviceSize
+	used       vk.DeviceSize
+	count      int
+	dedicated  bool
+	regions    []region
+	mapped     unsafe.Pointer
+}
+
+func newBlock(memory vk.DeviceMemory, memoryType uint32, size vk.DeviceSize, dedicated bool) *block {
+	return &block{
+		memory:     memory,
+		memoryType: memoryType,
+		size:       size,
+		dedicated:  dedicated,
+		regions:    []region{{offset: 0, size: size, free: true}},
+	}
+}
+
+// fit returns the offset a size byte allocation of kind would get in the
+// free region at index i, or false if it doesn't fit. Linear and non-linear
+// resources closer than granularity are moved onto separate pages.
+func (b *block) fit(i int, size, alignment, granularity vk.DeviceSize, kind Kind) (vk.DeviceSize, bool) {
+	r := b.regions[i]
+	offset := alignUp(r.offset, alignment)
+
+	if i > 0 {
+		prev := b.regions[i-1]
+		if !prev.free && conflicts(prev.kind, kind) && samePage(prev.end()-1, offset, granularity) {
+			offset = alignUp(offset, granularity)
+		}
+	}
+
+	end := offset + size
+	if end > r.end() {
+		return 0, false
+	}
+
+	if i+1 < len(b.regions) {
+		next := b.regions[i+1]
+		if !next.free && conflicts(kind, next.kind) && samePage(end-1, next.offset, granularity) {
+			return 0, false
+		}
+	}
+
+	return offset, true
+}
+
+// allocate places size bytes in the smallest free region they fit in.
+func (b *block) allocate(size, alignment, granularity vk.DeviceSize, kind Kind) (vk.DeviceSize, bool) {
+	best := -1
+	var bestOffset vk.DeviceSize
+	for i, r := range b.regions {
+		if !r.free || r.size < size {
+			continue
+		}
+		offset, ok := b.fit(i, size, alignment, granularity, kind)
+		if !ok {
+			continue
+		}
+		if best < 0 || r.size < b.regions[best].size {
+			best, bestOffset = i, offset
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+
+	r := b.regions[best]
+	split := make([]region, 0, 3)
+	if bestOffset > r.offset {
+		split = append(split, region{offset: r.offset, size: bestOffset - r.offset, free: true})
+	}
+	split = append(split, region{offset: bestOffset, size: size, kind: kind})
+	if end := bestOffset + size; end < r.end() {
+		split = append(split, region{offset: end, size: r.end() - end, free: true})
+	}
+
+	regions := make([]region, 0, len(b.regions)+len(split)-1)
+	regions = append(regions, b.regions[:best]...)
+	regions = append(regions, split...)
+	regions = append(regions, b.regions[best+1:]...)
+	b.regions = regions
+
+	b.used += size
+	b.count++
+
+	return bestOffset, true
+}
+
+// free releases the allocation at offset and merges it with free neighbours.
+func (b *block) free(offset vk.DeviceSize) {
+	i := b.find(offset)
+	if i < 0 {
+		return
+	}
+
+	b.used -= b.regions[i].size
+	b.count--
+	b.regions[i].free = true
+	b.regions[i].kind = KindUnknown
+
+	if i+1 < len(b.regions) && b.regions[i+1].free {
+		b.regions[i].size += b.regions[i+1].size
+		b.regions = append(b.regions[:i+1], b.regions[i+2:]...)
+	}
+	if i > 0 && b.regions[i-1].free {
+		b.regions[i-1].size += b.regions[i].size
+		b.regions = append(b.regions[:i], b.regions[i+1:]...)
+	}
+}
+
+func (b *block) find(offset vk.DeviceSize) int {
+	lo, hi := 0, len(b.regions)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		switch {
+		case b.regions[mid].offset == offset:
+			if b.regions[mid].free {
+				return -1
+			}
+			return mid
+		case b.regions[mid].offset < offset:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+
+	return -1
+}
+
+// largestFree is the size of the largest free region.
+func (b *block) largestFree() vk.DeviceSize {
+	var largest vk.DeviceSize
+	for _, r := range b.regions {
+		if r.free && r.size > largest {
+			largest = r.size
+		}
+	}
+	return largest
+}
+
+func alignUp(value, alignment vk.DeviceSize) vk.DeviceSize {
+	if alignment <= 1 {
+		return value
+	}
+	return (value + alignment - 1) / alignment * alignment
+}
+
+// samePage reports whether the bytes at a and b, a <= b, share a
+// bufferImageGranularity page.
+func samePage(a, b, granularity vk.DeviceSize) bool {
+	if granularity <= 1 {
+		return false
+	}
+	return a/granularity == b/granularity
+}
+
+// conflicts reports whether resources of kinds a and b must not share a
+// bufferImageGranularity page. Unknown kinds are treated as conflicting with
+// everything.
+func conflicts(a, b Kind) bool {
+	if a == KindUnknown || b == KindUnknown {
+		return true
+	}
+	return a.linear() != b.linear()
+}
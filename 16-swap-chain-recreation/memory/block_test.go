@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"reflect"
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func mustAllocate(t *testing.T, b *block, size, alignment, granularity vk.DeviceSize, kind Kind) vk.DeviceSize {
+	t.Helper()
+	offset, ok := b.allocate(size, alignment, granularity, kind)
+	if !ok {
+		t.Fatalf("allocating %d bytes of kind %d failed, regions %+v", size, kind, b.regions)
+	}
+	return offset
+}
+
+func TestBlockFreeCoalesces(t *testing.T) {
+	b := newBlock(vk.NullDeviceMemory, 0, 1024, false)
+	first := mustAllocate(t, b, 256, 1, 1, KindBuffer)
+	second := mustAllocate(t, b, 256, 1, 1, KindBuffer)
+	third := mustAllocate(t, b, 256, 1, 1, KindBuffer)
+
+	b.free(first)
+	b.free(third)
+	want := []region{
+		{offset: 0, size: 256, free: true},
+		{offset: 256, size: 256, kind: KindBuffer},
+		{offset: 512, size: 512, free: true},
+	}
+	if !reflect.DeepEqual(b.regions, want) {
+		t.Fatalf("got regions %+v, want %+v", b.regions, want)
+	}
+
+	b.free(second)
+	want = []region{{offset: 0, size: 1024, free: true}}
+	if !reflect.DeepEqual(b.regions, want) {
+		t.Fatalf("got regions %+v, want %+v", b.regions, want)
+	}
+	if b.used != 0 || b.count != 0 {
+		t.Errorf("got used %d and count %d after freeing everything", b.used, b.count)
+	}
+}
+
+func TestBlockAlignment(t *testing.T) {
+	b := newBlock(vk.NullDeviceMemory, 0, 1024, false)
+	mustAllocate(t, b, 10, 1, 1, KindBuffer)
+	offset := mustAllocate(t, b, 16, 256, 1, KindBuffer)
+	if offset != 256 {
+		t.Fatalf("got offset %d, want 256", offset)
+	}
+
+	want := []region{
+		{offset: 0, size: 10, kind: KindBuffer},
+		{offset: 10, size: 246, free: true},
+		{offset: 256, size: 16, kind: KindBuffer},
+		{offset: 272, size: 752, free: true},
+	}
+	if !reflect.DeepEqual(b.regions, want) {
+		t.Errorf("got regions %+v, want %+v", b.regions, want)
+	}
+	if b.used != 26 {
+		t.Errorf("got used %d, want 26 without the padding", b.used)
+	}
+}
+
+func TestBlockGranularity(t *testing.T) {
+	const granularity = 1024
+
+	tests := []struct {
+		name  string
+		first Kind
+		next  Kind
+		want  vk.DeviceSize
+	}{
+		{"buffer then optimal image", KindBuffer, KindImageOptimal, granularity},
+		{"optimal image then buffer", KindImageOptimal, KindBuffer, granularity},
+		{"buffer then linear image", KindBuffer, KindImageLinear, 112},
+		{"optimal images", KindImageOptimal, KindImageOptimal, 112},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := newBlock(vk.NullDeviceMemory, 0, 4*granularity, false)
+			mustAllocate(t, b, 100, 16, granularity, test.first)
+			offset := mustAllocate(t, b, 100, 16, granularity, test.next)
+			if offset != test.want {
+				t.Errorf("got offset %d, want %d", offset, test.want)
+			}
+		})
+	}
+}
+
+func TestBlockGranularityNextNeighbour(t *testing.T) {
+	const granularity = 1024
+	b := newBlock(vk.NullDeviceMemory, 0, 4*granularity, false)
+	first := mustAllocate(t, b, 1000, 1, granularity, KindBuffer)
+	mustAllocate(t, b, 1000, 1, granularity, KindBuffer)
+	b.free(first)
+
+	// The image fits in the hole at the start, but would share the page of
+	// the buffer after it, so it goes after that buffer, on the next page.
+	offset := mustAllocate(t, b, 500, 1, granularity, KindImageOptimal)
+	if offset != 2*granularity {
+		t.Errorf("got offset %d, want %d", offset, 2*granularity)
+	}
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// HeapBudget is how much of a memory heap the app may use and how much it
+// uses.
+type HeapBudget struct {
+	Heap        uint32
+	Size        vk.DeviceSize
+	DeviceLocal bool
+	// Budget is how much the app can allocate from the heap before
+	// allocations are likely to fail or hurt performance.
+	Budget vk.DeviceSize
+	// Usage is how much of the heap the app uses.
+	Usage vk.DeviceSize
+	// Estimated is set when Budget and Usage are estimates, see
+	// Allocator.Budget.
+	Estimated bool
+}
+
+// Fraction is Usage relative to Budget.
+func (b HeapBudget) Fraction() float64 {
+	if b.Budget == 0 {
+		return 0
+	}
+	return float64(b.Usage) / float64(b.Budget)
+}
+
+// Budget returns the budget and usage of every heap.
+//
+// They come from Config.QueryBudget, VK_EXT_memory_budget, when it is set
+// and succeeds, its usage counts all memory of the process. Otherwise the
+// numbers are estimates: the budget is 80% of the heap size, leaving room
+// for other processes and the driver, and the usage is the memory reserved
+// by this Allocator. Memory allocated elsewhere, such as swapchain images,
+// isn't counted then.
+func (a *Allocator) Budget() []HeapBudget {
+	var budget, usage []vk.DeviceSize
+	var ok bool
+	if a.queryBudget != nil {
+		budget, usage, ok = a.queryBudget()
+	}
+	ok = ok && len(budget) >= len(a.memoryHeaps) && len(usage) >= len(a.memoryHeaps)
+
+	stats := a.Stats()
+
+	budgets := make([]HeapBudget, len(a.memoryHeaps))
+	for i, heap := range a.memoryHeaps {
+		budgets[i] = HeapBudget{
+			Heap:        uint32(i),
+			Size:        heap.Size,
+			DeviceLocal: heap.Flags&vk.MemoryHeapFlags(vk.MemoryHeapDeviceLocalBit) != 0,
+			Budget:      heap.Size / 10 * 8,
+			Usage:       stats.Heaps[i].Reserved,
+			Estimated:   true,
+		}
+		if ok {
+			budgets[i].Budget = budget[i]
+			budgets[i].Usage = usage[i]
+			budgets[i].Estimated = false
+		}
+	}
+
+	return budgets
+}
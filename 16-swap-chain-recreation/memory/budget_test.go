@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"reflect"
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestBudget(t *testing.T) {
+	const heapSize = 1000
+
+	tests := []struct {
+		name        string
+		queryBudget func() (budget, usage []vk.DeviceSize, ok bool)
+		want        HeapBudget
+	}{
+		{
+			name: "no query",
+			want: HeapBudget{Size: heapSize, Budget: 800, Usage: 100, Estimated: true},
+		},
+		{
+			name: "query",
+			queryBudget: func() ([]vk.DeviceSize, []vk.DeviceSize, bool) {
+				return []vk.DeviceSize{900}, []vk.DeviceSize{300}, true
+			},
+			want: HeapBudget{Size: heapSize, Budget: 900, Usage: 300},
+		},
+		{
+			name: "query fails",
+			queryBudget: func() ([]vk.DeviceSize, []vk.DeviceSize, bool) {
+				return nil, nil, false
+			},
+			want: HeapBudget{Size: heapSize, Budget: 800, Usage: 100, Estimated: true},
+		},
+		{
+			name: "query misses heaps",
+			queryBudget: func() ([]vk.DeviceSize, []vk.DeviceSize, bool) {
+				return []vk.DeviceSize{}, []vk.DeviceSize{}, true
+			},
+			want: HeapBudget{Size: heapSize, Budget: 800, Usage: 100, Estimated: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := &Allocator{
+				memoryTypes: []vk.MemoryType{{HeapIndex: 0}},
+				memoryHeaps: []vk.MemoryHeap{{Size: heapSize}},
+				blocks:      [][]*block{{newBlock(vk.NullDeviceMemory, 0, 100, false)}},
+				queryBudget: test.queryBudget,
+			}
+
+			got := a.Budget()
+			if want := []HeapBudget{test.want}; !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
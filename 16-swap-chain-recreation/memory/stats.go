@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"fmt"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Stats are the usage statistics of an Allocator. Reserved bytes are those
+// in vk.DeviceMemory blocks, Used bytes those handed out as Allocations.
+type Stats struct {
+	// DeviceAllocations is the number of live vk.DeviceMemory objects, and
+	// MaxDeviceAllocations the device's maxMemoryAllocationCount.
+	DeviceAllocations    uint32
+	MaxDeviceAllocations uint32
+	Total                HeapStats
+	Heaps                []HeapStats
+}
+
+type HeapStats struct {
+	Blocks      int
+	Allocations int
+	Reserved    vk.DeviceSize
+	Used        vk.DeviceSize
+	// LargestFree is the largest allocation that fits in an existing block
+	// without allocating a new one.
+	LargestFree vk.DeviceSize
+}
+
+func (s *HeapStats) add(other HeapStats) {
+	s.Blocks += other.Blocks
+	s.Allocations += other.Allocations
+	s.Reserved += other.Reserved
+	s.Used += other.Used
+	if other.LargestFree > s.LargestFree {
+		s.LargestFree = other.LargestFree
+	}
+}
+
+func (s HeapStats) String() string {
+	return fmt.Sprintf("%d allocations in %d blocks, %s used of %s reserved", s.Allocations, s.Blocks, FormatBytes(s.Used), FormatBytes(s.Reserved))
+}
+
+// Stats returns the current usage, per heap and in total.
+func (a *Allocator) Stats() Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats := Stats{
+		DeviceAllocations:    a.deviceAllocations,
+		MaxDeviceAllocations: a.maxAllocationCount,
+		Heaps:                make([]HeapStats, len(a.memoryHeaps)),
+	}
+
+	for memoryType, blocks := range a.blocks {
+		heap := &stats.Heaps[a.memoryTypes[memoryType].HeapIndex]
+		for _, b := range blocks {
+			heap.add(HeapStats{
+				Blocks:      1,
+				Allocations: b.count,
+				Reserved:    b.size,
+				Used:        b.used,
+				LargestFree: b.largestFree(),
+			})
+		}
+	}
+
+	for _, heap := range stats.Heaps {
+		stats.Total.add(heap)
+	}
+
+	return stats
+}
+
+// FormatBytes formats n with a binary unit, e.g. 1.5 MiB.
+func FormatBytes(n vk.DeviceSize) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := vk.DeviceSize(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,294 @@
+package shaders
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrNoCompiler is returned by NewCompiler when neither glslc nor
+// glslangValidator is on the PATH.
+var ErrNoCompiler = errors.New("no shader compiler found, install glslc or glslangValidator")
+
+// Optimization is the optimization level passed to the compiler.
+type Optimization int
+
+const (
+	// OptimizeDefault leaves the level to the compiler.
+	OptimizeDefault Optimization = iota
+	OptimizeNone
+	OptimizePerformance
+	OptimizeSize
+)
+
+type Options struct {
+	// IncludePaths are searched for #include directives after the
+	// directory of the including file.
+	IncludePaths []string
+	// Defines are passed as -DNAME=VALUE, or -DNAME when VALUE is empty.
+	Defines      map[string]string
+	Optimization Optimization
+	// CacheDir holds compiled SPIR-V keyed by a hash of the source, its
+	// includes, the options and the compiler version. Defaults to a
+	// directory under os.UserCacheDir, caching is off if there is none.
+	CacheDir string
+}
+
+// Compiler compiles GLSL and HLSL shaders to SPIR-V with glslc, or
+// glslangValidator when glslc isn't installed.
+//
+// The shader stage is taken from the file extension: .vert, .frag, .comp,
+// .geom, .tesc or .tese. HLSL files carry it before the .hlsl suffix, e.g.
+// shader.frag.hlsl, and use main as their entry point.
+type Compiler struct {
+	tool    string
+	path    string
+	version string
+	options Options
+}
+
+func NewCompiler(options Options) (*Compiler, error) {
+	c := &Compiler{options: options}
+	for _, tool := range []string{"glslc", "glslangValidator"} {
+		path, err := exec.LookPath(tool)
+		if err == nil {
+			c.tool, c.path = tool, path
+			break
+		}
+	}
+	if c.path == "" {
+		return nil, ErrNoCompiler
+	}
+
+	version, err := exec.Command(c.path, "--version").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s --version: %w", c.tool, err)
+	}
+	c.version = string(version)
+
+	if c.options.CacheDir == "" {
+		if dir, err := os.UserCacheDir(); err == nil {
+			c.options.CacheDir = filepath.Join(dir, "vulkan-tutorial-go", "shaders")
+		}
+	}
+
+	return c, nil
+}
+
+// Tool is the name of the compiler in use.
+func (c *Compiler) Tool() string {
+	return c.tool
+}
+
+// Compile returns the SPIR-V for the shader source at path, from the cache
+// when the source, its includes and the options are unchanged.
+func (c *Compiler) Compile(path string) ([]byte, error) {
+	stage, hlsl, err := shaderStage(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := c.cacheKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cachePath string
+	if c.options.CacheDir != "" {
+		cachePath = filepath.Join(c.options.CacheDir, key+".spv")
+		if code, err := os.ReadFile(cachePath); err == nil {
+			return code, nil
+		}
+	}
+
+	out, err := os.CreateTemp("", "shader-*.spv")
+	if err != nil {
+		return nil, err
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+
+	cmd := exec.Command(c.path, c.args(path, out.Name(), stage, hlsl)...)
+	var stderr bytes.Buffer
+	cmd.Stdout = &stderr
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s %s: %w\n%s", c.tool, path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	code, err := os.ReadFile(out.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	if cachePath != "" {
+		// A failed cache write only costs a recompile next time.
+		_ = writeFileAtomic(cachePath, code)
+	}
+
+	return code, nil
+}
+
+func (c *Compiler) args(src, out, stage string, hlsl bool) []string {
+	var args []string
+	if c.tool == "glslc" {
+		args = append(args, "-fshader-stage="+stage)
+		if hlsl {
+			args = append(args, "-x", "hlsl", "-fentry-point=main")
+		}
+		switch c.options.Optimization {
+		case OptimizeNone:
+			args = append(args, "-O0")
+		case OptimizePerformance:
+			args = append(args, "-O")
+		case OptimizeSize:
+			args = append(args, "-Os")
+		}
+	} else {
+		args = append(args, "-V", "-S", stage)
+		if hlsl {
+			args = append(args, "-D", "-e", "main")
+		}
+		// glslangValidator has no performance level, it only optimizes
+		// for size.
+		switch c.options.Optimization {
+		case OptimizeNone:
+			args = append(args, "-Od")
+		case OptimizeSize:
+			args = append(args, "-Os")
+		}
+	}
+
+	for _, dir := range c.options.IncludePaths {
+		args = append(args, "-I"+dir)
+	}
+	for _, define := range c.defines() {
+		args = append(args, "-D"+define)
+	}
+
+	return append(args, "-o", out, src)
+}
+
+// defines returns the defines as sorted NAME=VALUE strings, so they hash the
+// same every time.
+func (c *Compiler) defines() []string {
+	defines := make([]string, 0, len(c.options.Defines))
+	for name, value := range c.options.Defines {
+		if value == "" {
+			defines = append(defines, name)
+		} else {
+			defines = append(defines, name+"="+value)
+		}
+	}
+	sort.Strings(defines)
+
+	return defines
+}
+
+// cacheKey hashes everything that affects the output of compiling path.
+func (c *Compiler) cacheKey(path string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00", c.tool, c.version, c.options.Optimization)
+	for _, define := range c.defines() {
+		fmt.Fprintf(h, "D%s\x00", define)
+	}
+	for _, dir := range c.options.IncludePaths {
+		fmt.Fprintf(h, "I%s\x00", dir)
+	}
+
+	err := c.hashSource(h, path, make(map[string]bool))
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+var includePattern = regexp.MustCompile(`^\s*#\s*include\s*["<]([^">]+)[">]`)
+
+// hashSource hashes the file at path and, recursively, every file it
+// includes that can be found.
+func (c *Compiler) hashSource(h io.Writer, path string, seen map[string]bool) error {
+	if seen[path] {
+		return nil
+	}
+	seen[path] = true
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(h, "F%s\x00%d\x00", filepath.Base(path), len(source))
+	h.Write(source)
+
+	dirs := append([]string{filepath.Dir(path)}, c.options.IncludePaths...)
+	scanner := bufio.NewScanner(bytes.NewReader(source))
+	for scanner.Scan() {
+		match := includePattern.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		for _, dir := range dirs {
+			include := filepath.Join(dir, match[1])
+			if _, err := os.Stat(include); err == nil {
+				if err := c.hashSource(h, include, seen); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// shaderStage returns the stage name for the source at path and whether it
+// is HLSL.
+func shaderStage(path string) (string, bool, error) {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	hlsl := ext == ".hlsl"
+	if hlsl || ext == ".glsl" {
+		ext = filepath.Ext(strings.TrimSuffix(name, ext))
+	}
+
+	switch stage := strings.TrimPrefix(ext, "."); stage {
+	case "vert", "frag", "comp", "geom", "tesc", "tese":
+		return stage, hlsl, nil
+	default:
+		return "", false, fmt.Errorf("%s: can't tell the shader stage from the file extension", path)
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,234 @@
+package shaders
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestShaderStage(t *testing.T) {
+	tests := []struct {
+		path  string
+		stage string
+		hlsl  bool
+		err   bool
+	}{
+		{path: "shader.vert", stage: "vert"},
+		{path: "shaders/shader.frag", stage: "frag"},
+		{path: "particles.comp", stage: "comp"},
+		{path: "lines.geom", stage: "geom"},
+		{path: "patch.tesc", stage: "tesc"},
+		{path: "patch.tese", stage: "tese"},
+		{path: "shader.vert.glsl", stage: "vert"},
+		{path: "shader.frag.hlsl", stage: "frag", hlsl: true},
+		{path: "shader.txt", err: true},
+		{path: "shader.spv", err: true},
+		{path: "shader.glsl", err: true},
+		{path: "shader.hlsl", err: true},
+		{path: "shader", err: true},
+	}
+
+	for _, tt := range tests {
+		stage, hlsl, err := shaderStage(tt.path)
+		if tt.err {
+			if err == nil {
+				t.Errorf("shaderStage(%q) = %q, want an error", tt.path, stage)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("shaderStage(%q): %v", tt.path, err)
+			continue
+		}
+		if stage != tt.stage || hlsl != tt.hlsl {
+			t.Errorf("shaderStage(%q) = %q, %v, want %q, %v", tt.path, stage, hlsl, tt.stage, tt.hlsl)
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	options := Options{
+		IncludePaths: []string{"include", "lib"},
+		Defines:      map[string]string{"SAMPLES": "4", "DEBUG": ""},
+	}
+
+	tests := []struct {
+		name         string
+		tool         string
+		hlsl         bool
+		optimization Optimization
+		want         []string
+	}{
+		{
+			name: "glslc",
+			tool: "glslc",
+			want: []string{"-fshader-stage=frag", "-Iinclude", "-Ilib", "-DDEBUG", "-DSAMPLES=4", "-o", "out.spv", "src"},
+		},
+		{
+			name:         "glslc hlsl",
+			tool:         "glslc",
+			hlsl:         true,
+			optimization: OptimizePerformance,
+			want:         []string{"-fshader-stage=frag", "-x", "hlsl", "-fentry-point=main", "-O", "-Iinclude", "-Ilib", "-DDEBUG", "-DSAMPLES=4", "-o", "out.spv", "src"},
+		},
+		{
+			name:         "glslc no optimization",
+			tool:         "glslc",
+			optimization: OptimizeNone,
+			want:         []string{"-fshader-stage=frag", "-O0", "-Iinclude", "-Ilib", "-DDEBUG", "-DSAMPLES=4", "-o", "out.spv", "src"},
+		},
+		{
+			name:         "glslc size",
+			tool:         "glslc",
+			optimization: OptimizeSize,
+			want:         []string{"-fshader-stage=frag", "-Os", "-Iinclude", "-Ilib", "-DDEBUG", "-DSAMPLES=4", "-o", "out.spv", "src"},
+		},
+		{
+			name: "glslangValidator",
+			tool: "glslangValidator",
+			want: []string{"-V", "-S", "frag", "-Iinclude", "-Ilib", "-DDEBUG", "-DSAMPLES=4", "-o", "out.spv", "src"},
+		},
+		{
+			name:         "glslangValidator hlsl",
+			tool:         "glslangValidator",
+			hlsl:         true,
+			optimization: OptimizeSize,
+			want:         []string{"-V", "-S", "frag", "-D", "-e", "main", "-Os", "-Iinclude", "-Ilib", "-DDEBUG", "-DSAMPLES=4", "-o", "out.spv", "src"},
+		},
+		{
+			name:         "glslangValidator no optimization",
+			tool:         "glslangValidator",
+			optimization: OptimizeNone,
+			want:         []string{"-V", "-S", "frag", "-Od", "-Iinclude", "-Ilib", "-DDEBUG", "-DSAMPLES=4", "-o", "out.spv", "src"},
+		},
+		{
+			name:         "glslangValidator performance",
+			tool:         "glslangValidator",
+			optimization: OptimizePerformance,
+			want:         []string{"-V", "-S", "frag", "-Iinclude", "-Ilib", "-DDEBUG", "-DSAMPLES=4", "-o", "out.spv", "src"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Compiler{tool: tt.tool, options: options}
+			c.options.Optimization = tt.optimization
+
+			got := c.args("src", "out.spv", "frag", tt.hlsl)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	includeDir := filepath.Join(dir, "include")
+	write := func(path, source string) {
+		t.Helper()
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(source), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// common.glsl and light.glsl include each other, light.glsl is only
+	// found through the include path.
+	shader := filepath.Join(dir, "shader.frag")
+	common := filepath.Join(dir, "common.glsl")
+	light := filepath.Join(includeDir, "light.glsl")
+	write(shader, "#version 450\n#include \"common.glsl\"\nvoid main() {}\n")
+	write(common, "#include <light.glsl>\nconst float pi = 3.14159;\n")
+	write(light, "#include \"common.glsl\"\nconst vec3 ambient = vec3(0.1);\n")
+
+	newCompiler := func() *Compiler {
+		return &Compiler{
+			tool:    "glslc",
+			version: "shaderc v2023.1",
+			options: Options{
+				IncludePaths: []string{includeDir},
+				Defines:      map[string]string{"SAMPLES": "4"},
+				Optimization: OptimizePerformance,
+			},
+		}
+	}
+	key := func(c *Compiler) string {
+		t.Helper()
+		k, err := c.cacheKey(shader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	base := key(newCompiler())
+	if again := key(newCompiler()); again != base {
+		t.Errorf("key changed without changes: %s, then %s", base, again)
+	}
+
+	c := newCompiler()
+	c.options.Defines["SAMPLES"] = "8"
+	if key(c) == base {
+		t.Error("key unchanged after changing a define")
+	}
+
+	c = newCompiler()
+	c.options.Defines["DEBUG"] = ""
+	if key(c) == base {
+		t.Error("key unchanged after adding a define")
+	}
+
+	c = newCompiler()
+	c.options.Optimization = OptimizeSize
+	if key(c) == base {
+		t.Error("key unchanged after changing the optimization level")
+	}
+
+	c = newCompiler()
+	c.version = "shaderc v2024.0"
+	if key(c) == base {
+		t.Error("key unchanged after changing the compiler version")
+	}
+
+	write(light, "#include \"common.glsl\"\nconst vec3 ambient = vec3(0.2);\n")
+	if key(newCompiler()) == base {
+		t.Error("key unchanged after changing an included file")
+	}
+
+	write(light, "#include \"common.glsl\"\nconst vec3 ambient = vec3(0.1);\n")
+	if restored := key(newCompiler()); restored != base {
+		t.Errorf("key = %s after restoring the included file, want %s", restored, base)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "shader.spv")
+
+	for _, data := range []string{"first", "second"} {
+		err := writeFileAtomic(path, []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != data {
+			t.Errorf("read %q, want %q", got, data)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("cache directory has %d entries, want only the file", len(entries))
+	}
+}
@@ -0,0 +1,11 @@
+// Package shaders embeds the compiled SPIR-V of the chapter's shaders, so
+// binaries don't need the source tree at run time. Regenerate vert.spv and
+// frag.spv after editing shader.vert or shader.frag.
+package shaders
+
+import "embed"
+
+// FS holds vert.spv and frag.spv.
+//
+//go:embed *.spv
+var FS embed.FS
@@ -0,0 +1,236 @@
+package spirv
+
+import (
+	"fmt"
+	"sort"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// attributeFormats maps the component type and width of a variable to the
+// formats with one to four components.
+var attributeFormats = map[ScalarType]map[uint32][4]vk.Format{
+	ScalarFloat: {
+		16: {vk.FormatR16Sfloat, vk.FormatR16g16Sfloat, vk.FormatR16g16b16Sfloat, vk.FormatR16g16b16a16Sfloat},
+		32: {vk.FormatR32Sfloat, vk.FormatR32g32Sfloat, vk.FormatR32g32b32Sfloat, vk.FormatR32g32b32a32Sfloat},
+		64: {vk.FormatR64Sfloat, vk.FormatR64g64Sfloat, vk.FormatR64g64b64Sfloat, vk.FormatR64g64b64a64Sfloat},
+	},
+	ScalarInt: {
+		16: {vk.FormatR16Sint, vk.FormatR16g16Sint, vk.FormatR16g16b16Sint, vk.FormatR16g16b16a16Sint},
+		32: {vk.FormatR32Sint, vk.FormatR32g32Sint, vk.FormatR32g32b32Sint, vk.FormatR32g32b32a32Sint},
+		64: {vk.FormatR64Sint, vk.FormatR64g64Sint, vk.FormatR64g64b64Sint, vk.FormatR64g64b64a64Sint},
+	},
+	ScalarUint: {
+		16: {vk.FormatR16Uint, vk.FormatR16g16Uint, vk.FormatR16g16b16Uint, vk.FormatR16g16b16a16Uint},
+		32: {vk.FormatR32Uint, vk.FormatR32g32Uint, vk.FormatR32g32b32Uint, vk.FormatR32g32b32a32Uint},
+		64: {vk.FormatR64Uint, vk.FormatR64g64Uint, vk.FormatR64g64b64Uint, vk.FormatR64g64b64a64Uint},
+	},
+}
+
+func attributeFormat(t ScalarType, width, components uint32) vk.Format {
+	formats, ok := attributeFormats[t][width]
+	if !ok || components < 1 || components > 4 {
+		return vk.FormatUndefined
+	}
+	return formats[components-1]
+}
+
+// integerFormats are the vertex attribute formats read as signed or unsigned
+// integers. Every other format is read as floating point.
+var integerFormats = map[vk.Format]ScalarType{
+	vk.FormatR8Uint: ScalarUint, vk.FormatR8Sint: ScalarInt,
+	vk.FormatR8g8Uint: ScalarUint, vk.FormatR8g8Sint: ScalarInt,
+	vk.FormatR8g8b8Uint: ScalarUint, vk.FormatR8g8b8Sint: ScalarInt,
+	vk.FormatB8g8r8Uint: ScalarUint, vk.FormatB8g8r8Sint: ScalarInt,
+	vk.FormatR8g8b8a8Uint: ScalarUint, vk.FormatR8g8b8a8Sint: ScalarInt,
+	vk.FormatB8g8r8a8Uint: ScalarUint, vk.FormatB8g8r8a8Sint: ScalarInt,
+	vk.FormatA8b8g8r8UintPack32: ScalarUint, vk.FormatA8b8g8r8SintPack32: ScalarInt,
+	vk.FormatA2r10g10b10UintPack32: ScalarUint, vk.FormatA2r10g10b10SintPack32: ScalarInt,
+	vk.FormatA2b10g10r10UintPack32: ScalarUint, vk.FormatA2b10g10r10SintPack32: ScalarInt,
+	vk.FormatR16Uint: ScalarUint, vk.FormatR16Sint: ScalarInt,
+	vk.FormatR16g16Uint: ScalarUint, vk.FormatR16g16Sint: ScalarInt,
+	vk.FormatR16g16b16Uint: ScalarUint, vk.FormatR16g16b16Sint: ScalarInt,
+	vk.FormatR16g16b16a16Uint: ScalarUint, vk.FormatR16g16b16a16Sint: ScalarInt,
+	vk.FormatR32Uint: ScalarUint, vk.FormatR32Sint: ScalarInt,
+	vk.FormatR32g32Uint: ScalarUint, vk.FormatR32g32Sint: ScalarInt,
+	vk.FormatR32g32b32Uint: ScalarUint, vk.FormatR32g32b32Sint: ScalarInt,
+	vk.FormatR32g32b32a32Uint: ScalarUint, vk.FormatR32g32b32a32Sint: ScalarInt,
+	vk.FormatR64Uint: ScalarUint, vk.FormatR64Sint: ScalarInt,
+	vk.FormatR64g64Uint: ScalarUint, vk.FormatR64g64Sint: ScalarInt,
+	vk.FormatR64g64b64Uint: ScalarUint, vk.FormatR64g64b64Sint: ScalarInt,
+	vk.FormatR64g64b64a64Uint: ScalarUint, vk.FormatR64g64b64a64Sint: ScalarInt,
+}
+
+func formatType(format vk.Format) ScalarType {
+	if t, ok := integerFormats[format]; ok {
+		return t
+	}
+	return ScalarFloat
+}
+
+// SetLayoutBindings merges the descriptor bindings of the entry points of a
+// pipeline into the bindings of each descriptor set, indexed by set number.
+// Sets no entry point uses are left empty. A binding used by several stages
+// must have the same type and count in all of them.
+func SetLayoutBindings(entries ...EntryPoint) ([][]vk.DescriptorSetLayoutBinding, error) {
+	type key struct{ set, binding uint32 }
+	merged := make(map[key]*vk.DescriptorSetLayoutBinding)
+	var keys []key
+	var sets uint32
+
+	for _, entry := range entries {
+		for _, b := range entry.Bindings {
+			k := key{b.Set, b.Binding}
+			if existing, ok := merged[k]; ok {
+				if existing.DescriptorType != b.Type || existing.DescriptorCount != b.Count {
+					return nil, fmt.Errorf("set %d binding %d is %s[%d] in one stage and %s[%d] in another",
+						b.Set, b.Binding, descriptorTypeName(existing.DescriptorType), existing.DescriptorCount, descriptorTypeName(b.Type), b.Count)
+				}
+				existing.StageFlags |= vk.ShaderStageFlags(entry.Stage)
+				continue
+			}
+			if b.Count == 0 {
+				return nil, fmt.Errorf("set %d binding %d (%s) is a runtime array, which needs descriptor indexing", b.Set, b.Binding, b.Name)
+			}
+
+			merged[k] = &vk.DescriptorSetLayoutBinding{
+				Binding:         b.Binding,
+				DescriptorType:  b.Type,
+				DescriptorCount: b.Count,
+				StageFlags:      vk.ShaderStageFlags(entry.Stage),
+			}
+			keys = append(keys, k)
+			if b.Set+1 > sets {
+				sets = b.Set + 1
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].set < keys[j].set || keys[i].set == keys[j].set && keys[i].binding < keys[j].binding
+	})
+	layouts := make([][]vk.DescriptorSetLayoutBinding, sets)
+	for _, k := range keys {
+		layouts[k.set] = append(layouts[k.set], *merged[k])
+	}
+
+	return layouts, nil
+}
+
+// PushConstantRanges returns the push constant ranges of a pipeline, one per
+// distinct range with the stages that read it.
+func PushConstantRanges(entries ...EntryPoint) []vk.PushConstantRange {
+	var ranges []vk.PushConstantRange
+	for _, entry := range entries {
+	next:
+		for _, r := range entry.PushConstants {
+			for i := range ranges {
+				if ranges[i].Offset == r.Offset && ranges[i].Size == r.Size {
+					ranges[i].StageFlags |= vk.ShaderStageFlags(entry.Stage)
+					continue next
+				}
+			}
+			ranges = append(ranges, vk.PushConstantRange{
+				StageFlags: vk.ShaderStageFlags(entry.Stage),
+				Offset:     r.Offset,
+				Size:       r.Size,
+			})
+		}
+	}
+
+	return ranges
+}
+
+// CheckVertexInput checks that every input of the vertex entry point is fed
+// by one of attributes with a format of the same component type.
+func CheckVertexInput(entry EntryPoint, attributes []vk.VertexInputAttributeDescription) error {
+	formats := make(map[uint32]vk.Format, len(attributes))
+	for _, attribute := range attributes {
+		formats[attribute.Location] = attribute.Format
+	}
+
+	for _, input := range entry.Inputs {
+		for location := input.Location; location < input.Location+input.Locations; location++ {
+			format, ok := formats[location]
+			if !ok {
+				return fmt.Errorf("vertex input %q at location %d has no vertex attribute", input.Name, location)
+			}
+			if formatType(format) != input.Type {
+				return fmt.Errorf("vertex input %q at location %d is %s, but its attribute format %d is read as %s",
+					input.Name, location, input.Type, format, formatType(format))
+			}
+		}
+	}
+
+	return nil
+}
+
+// CheckInterface checks that every input of consumer is written by an output
+// of producer, the stage before it, with the same component type and at
+// least as many components.
+func CheckInterface(producer, consumer EntryPoint) error {
+	outputs := make(map[uint32]Variable)
+	for _, output := range producer.Outputs {
+		for location := output.Location; location < output.Location+output.Locations; location++ {
+			outputs[location] = output
+		}
+	}
+
+	for _, input := range consumer.Inputs {
+		output, ok := outputs[input.Location]
+		if !ok {
+			return fmt.Errorf("%s input %q at location %d is not written by %s %q",
+				stageName(consumer.Stage), input.Name, input.Location, stageName(producer.Stage), producer.Name)
+		}
+		if output.Type != input.Type || output.Width != input.Width || output.Components < input.Components {
+			return fmt.Errorf("%s input %q at location %d is %s, but %s output %q is %s",
+				stageName(consumer.Stage), input.Name, input.Location, typeName(input), stageName(producer.Stage), output.Name, typeName(output))
+		}
+	}
+
+	return nil
+}
+
+func typeName(v Variable) string {
+	if v.Components == 1 {
+		return fmt.Sprintf("%s%d", v.Type, v.Width)
+	}
+	return fmt.Sprintf("%s%dx%d", v.Type, v.Width, v.Components)
+}
+
+func stageName(stage vk.ShaderStageFlagBits) string {
+	switch stage {
+	case vk.ShaderStageVertexBit:
+		return "vertex"
+	case vk.ShaderStageTessellationControlBit:
+		return "tessellation control"
+	case vk.ShaderStageTessellationEvaluationBit:
+		return "tessellation evaluation"
+	case vk.ShaderStageGeometryBit:
+		return "geometry"
+	case vk.ShaderStageFragmentBit:
+		return "fragment"
+	case vk.ShaderStageComputeBit:
+		return "compute"
+	}
+	return fmt.Sprintf("stage %d", stage)
+}
+
+var descriptorTypeNames = map[vk.DescriptorType]string{
+	vk.DescriptorTypeSampler:              "sampler",
+	vk.DescriptorTypeCombinedImageSampler: "combined image sampler",
+	vk.DescriptorTypeSampledImage:         "sampled image",
+	vk.DescriptorTypeStorageImage:         "storage image",
+	vk.DescriptorTypeUniformTexelBuffer:   "uniform texel buffer",
+	vk.DescriptorTypeStorageTexelBuffer:   "storage texel buffer",
+	vk.DescriptorTypeUniformBuffer:        "uniform buffer",
+	vk.DescriptorTypeStorageBuffer:        "storage buffer",
+	vk.DescriptorTypeInputAttachment:      "input attachment",
+}
+
+func descriptorTypeName(t vk.DescriptorType) string {
+	if name, ok := descriptorTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("descriptor type %d", t)
+}
@@ -0,0 +1,892 @@
+// Package spirv reflects SPIR-V modules: their entry points and, for each,
+// the stage, the input and output locations, the descriptor bindings and the
+// push constant ranges. It only decodes the words of the module, so it runs
+// without a GPU.
+package spirv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+var ErrInvalidModule = errors.New("invalid SPIR-V module")
+
+const (
+	magic = 0x07230203
+	// headerWords is the number of words before the first instruction:
+	// magic, version, generator, bound and schema.
+	headerWords = 5
+	// version14 is the first version whose entry points list every global
+	// variable they use, not just inputs and outputs.
+	version14 = 0x00010400
+	// maxTypeDepth is how deeply types may nest, through arrays, matrices and
+	// structs, before the module is taken to have a cyclic type.
+	maxTypeDepth = 64
+)
+
+const (
+	opName                      = 5
+	opMemberName                = 6
+	opEntryPoint                = 15
+	opTypeBool                  = 20
+	opTypeInt                   = 21
+	opTypeFloat                 = 22
+	opTypeVector                = 23
+	opTypeMatrix                = 24
+	opTypeImage                 = 25
+	opTypeSampler               = 26
+	opTypeSampledImage          = 27
+	opTypeArray                 = 28
+	opTypeRuntimeArray          = 29
+	opTypeStruct                = 30
+	opTypePointer               = 32
+	opConstant                  = 43
+	opSpecConstantTrue          = 48
+	opSpecConstantFalse         = 49
+	opSpecConstant              = 50
+	opVariable                  = 59
+	opDecorate                  = 71
+	opMemberDecorate            = 72
+	opTypeAccelerationStructure = 5341
+)
+
+// minOperands is the number of operand words an instruction needs before it
+// can be decoded.
+var minOperands = map[uint32]int{
+	opName:                      2,
+	opMemberName:                3,
+	opEntryPoint:                3,
+	opTypeBool:                  1,
+	opTypeInt:                   3,
+	opTypeFloat:                 2,
+	opTypeVector:                3,
+	opTypeMatrix:                3,
+	opTypeImage:                 8,
+	opTypeSampler:               1,
+	opTypeSampledImage:          2,
+	opTypeArray:                 3,
+	opTypeRuntimeArray:          2,
+	opTypeStruct:                1,
+	opTypePointer:               3,
+	opConstant:                  3,
+	opSpecConstantTrue:          2,
+	opSpecConstantFalse:         2,
+	opSpecConstant:              3,
+	opVariable:                  3,
+	opDecorate:                  2,
+	opMemberDecorate:            3,
+	opTypeAccelerationStructure: 1,
+}
+
+const (
+	decorationSpecID        = 1
+	decorationBlock         = 2
+	decorationBufferBlock   = 3
+	decorationArrayStride   = 6
+	decorationMatrixStride  = 7
+	decorationBuiltIn       = 11
+	decorationLocation      = 30
+	decorationBinding       = 33
+	decorationDescriptorSet = 34
+	decorationOffset        = 35
+)
+
+const (
+	storageUniformConstant = 0
+	storageInput           = 1
+	storageUniform         = 2
+	storageOutput          = 3
+	storagePushConstant    = 9
+	storageStorageBuffer   = 12
+)
+
+const (
+	dimBuffer      = 5
+	dimSubpassData = 6
+)
+
+var executionModelStages = map[uint32]vk.ShaderStageFlagBits{
+	0: vk.ShaderStageVertexBit,
+	1: vk.ShaderStageTessellationControlBit,
+	2: vk.ShaderStageTessellationEvaluationBit,
+	3: vk.ShaderStageGeometryBit,
+	4: vk.ShaderStageFragmentBit,
+	5: vk.ShaderStageComputeBit,
+}
+
+// Module is the reflection of a SPIR-V module.
+type Module struct {
+	// Version is the SPIR-V version, major<<16 | minor<<8.
+	Version     uint32
+	EntryPoints []EntryPoint
+	// SpecConstants are sorted by ID.
+	SpecConstants []SpecConstant
+}
+
+// SpecConstant is a specialization constant, layout(constant_id = ID) in
+// GLSL.
+type SpecConstant struct {
+	Name string
+	ID   uint32
+	Type ScalarType
+	// Size is the number of bytes the value takes in
+	// vk.SpecializationInfo, 4 for booleans.
+	Size uint32
+}
+
+// EntryPoint returns the entry point called name for stage.
+func (m *Module) EntryPoint(name string, stage vk.ShaderStageFlagBits) (EntryPoint, bool) {
+	for _, entry := range m.EntryPoints {
+		if entry.Name == name && entry.Stage == stage {
+			return entry, true
+		}
+	}
+	return EntryPoint{}, false
+}
+
+// SpecConstant returns the specialization constant with the given ID.
+func (m *Module) SpecConstant(id uint32) (SpecConstant, bool) {
+	for _, constant := range m.SpecConstants {
+		if constant.ID == id {
+			return constant, true
+		}
+	}
+	return SpecConstant{}, false
+}
+
+// EntryPoint is a shader entry point and the resources it uses.
+//
+// Before SPIR-V 1.4 entry points only declare their inputs and outputs, so
+// Bindings and PushConstants hold every resource of the module for them.
+type EntryPoint struct {
+	Name  string
+	Stage vk.ShaderStageFlagBits
+	// Inputs and Outputs are sorted by location. Built-ins such as
+	// gl_Position are left out.
+	Inputs  []Variable
+	Outputs []Variable
+	// Bindings are sorted by set, then binding.
+	Bindings      []Binding
+	PushConstants []PushConstantRange
+}
+
+// ScalarType is the component type of an input or output.
+type ScalarType int
+
+const (
+	ScalarFloat ScalarType = iota
+	ScalarInt
+	ScalarUint
+	// ScalarBool is only used by specialization constants, booleans can't
+	// be inputs or outputs.
+	ScalarBool
+)
+
+func (t ScalarType) String() string {
+	switch t {
+	case ScalarInt:
+		return "int"
+	case ScalarUint:
+		return "uint"
+	case ScalarBool:
+		return "bool"
+	default:
+		return "float"
+	}
+}
+
+// Variable is an input or output of an entry point.
+type Variable struct {
+	Name     string
+	Location uint32
+	// Locations is the number of consecutive locations the variable takes,
+	// more than one for matrices and arrays.
+	Locations  uint32
+	Type       ScalarType
+	Width      uint32
+	Components uint32
+	// Format is the vertex attribute format matching one location of the
+	// variable exactly, vk.FormatUndefined when there is none.
+	Format vk.Format
+}
+
+// Binding is a descriptor binding.
+//
+// SPIR-V doesn't say whether a buffer is bound with a dynamic offset, uniform
+// and storage buffers are reported as vk.DescriptorTypeUniformBuffer and
+// vk.DescriptorTypeStorageBuffer.
+type Binding struct {
+	Name    string
+	Set     uint32
+	Binding uint32
+	Type    vk.DescriptorType
+	// Count is the number of descriptors, more than one for arrays and 0
+	// for runtime arrays.
+	Count uint32
+}
+
+// PushConstantRange is the part of the push constant block an entry point
+// reads.
+type PushConstantRange struct {
+	Name   string
+	Offset uint32
+	Size   uint32
+}
+
+type typeInfo struct {
+	op       uint32
+	operands []uint32
+}
+
+type variable struct {
+	id      uint32
+	typeID  uint32
+	storage uint32
+}
+
+type entryPoint struct {
+	model        uint32
+	name         string
+	interfaceIDs []uint32
+}
+
+type memberKey struct {
+	id, member uint32
+}
+
+type parser struct {
+	names             map[uint32]string
+	memberNames       map[memberKey]string
+	decorations       map[uint32]map[uint32]uint32
+	memberDecorations map[memberKey]map[uint32]uint32
+	types             map[uint32]typeInfo
+	constants         map[uint32]uint32
+	variables         map[uint32]variable
+	globals           []uint32
+	// specConstants maps the IDs of specialization constants to their types.
+	specConstants map[uint32]uint32
+	entryPoints   []entryPoint
+}
+
+// Decode converts a SPIR-V module into the words Parse and
+// vk.ShaderModuleCreateInfo take. A module is a stream of 32-bit words in the
+// byte order of the machine that produced it, the magic number tells which
+// one. Checking it here means the driver, which may crash on garbage instead
+// of failing, only ever sees something that looks like SPIR-V.
+func Decode(code []byte) ([]uint32, error) {
+	if len(code)%4 != 0 {
+		return nil, fmt.Errorf("%w: size %d is not a multiple of 4", ErrInvalidModule, len(code))
+	}
+	if len(code) < headerWords*4 {
+		return nil, fmt.Errorf("%w: %d bytes is too short for a module header", ErrInvalidModule, len(code))
+	}
+
+	var order binary.ByteOrder
+	switch {
+	case binary.LittleEndian.Uint32(code) == magic:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(code) == magic:
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("%w: magic number is 0x%08x, not 0x%08x", ErrInvalidModule, binary.LittleEndian.Uint32(code), magic)
+	}
+
+	words := make([]uint32, len(code)/4)
+	for i := range words {
+		words[i] = order.Uint32(code[i*4:])
+	}
+
+	return words, nil
+}
+
+// Parse reflects the SPIR-V module in words, which must be in host byte
+// order.
+func Parse(words []uint32) (*Module, error) {
+	if len(words) < headerWords {
+		return nil, fmt.Errorf("%w: %d words is too short for a module header", ErrInvalidModule, len(words))
+	}
+	if words[0] != magic {
+		return nil, fmt.Errorf("%w: magic number is 0x%08x, not 0x%08x", ErrInvalidModule, words[0], magic)
+	}
+
+	p := &parser{
+		names:             make(map[uint32]string),
+		memberNames:       make(map[memberKey]string),
+		decorations:       make(map[uint32]map[uint32]uint32),
+		memberDecorations: make(map[memberKey]map[uint32]uint32),
+		types:             make(map[uint32]typeInfo),
+		constants:         make(map[uint32]uint32),
+		variables:         make(map[uint32]variable),
+		specConstants:     make(map[uint32]uint32),
+	}
+	err := p.decode(words[headerWords:])
+	if err != nil {
+		return nil, err
+	}
+
+	module := &Module{Version: words[1]}
+	module.SpecConstants, err = p.specConstantList()
+	if err != nil {
+		return nil, err
+	}
+	for _, ep := range p.entryPoints {
+		entry, err := p.reflect(ep, module.Version)
+		if err != nil {
+			return nil, fmt.Errorf("entry point %q: %w", ep.name, err)
+		}
+		module.EntryPoints = append(module.EntryPoints, entry)
+	}
+
+	return module, nil
+}
+
+func (p *parser) decode(words []uint32) error {
+	for i := 0; i < len(words); {
+		op, count := words[i]&0xffff, int(words[i]>>16)
+		if count == 0 || i+count > len(words) {
+			return fmt.Errorf("%w: instruction %d at word %d overruns the module", ErrInvalidModule, op, headerWords+i)
+		}
+		operands := words[i+1 : i+count]
+		if len(operands) < minOperands[op] {
+			return fmt.Errorf("%w: instruction %d at word %d has %d operands, want %d", ErrInvalidModule, op, headerWords+i, len(operands), minOperands[op])
+		}
+		i += count
+
+		switch op {
+		case opName:
+			p.names[operands[0]], _ = literalString(operands[1:])
+		case opMemberName:
+			p.memberNames[memberKey{operands[0], operands[1]}], _ = literalString(operands[2:])
+		case opEntryPoint:
+			name, n := literalString(operands[2:])
+			p.entryPoints = append(p.entryPoints, entryPoint{
+				model:        operands[0],
+				name:         name,
+				interfaceIDs: operands[2+n:],
+			})
+		case opTypeBool, opTypeInt, opTypeFloat, opTypeVector, opTypeMatrix, opTypeImage, opTypeSampler,
+			opTypeSampledImage, opTypeArray, opTypeRuntimeArray, opTypeStruct, opTypePointer, opTypeAccelerationStructure:
+			p.types[operands[0]] = typeInfo{op: op, operands: operands[1:]}
+		case opConstant, opSpecConstant:
+			// Only the low word matters, constants are used for array
+			// lengths. Specialization constants take their default.
+			p.constants[operands[1]] = operands[2]
+			if op == opSpecConstant {
+				p.specConstants[operands[1]] = operands[0]
+			}
+		case opSpecConstantTrue, opSpecConstantFalse:
+			p.specConstants[operands[1]] = operands[0]
+		case opVariable:
+			v := variable{typeID: operands[0], id: operands[1], storage: operands[2]}
+			p.variables[v.id] = v
+			p.globals = append(p.globals, v.id)
+		case opDecorate:
+			p.decorate(operands[0], operands[1:])
+		case opMemberDecorate:
+			key := memberKey{operands[0], operands[1]}
+			decorations := p.memberDecorations[key]
+			if decorations == nil {
+				decorations = make(map[uint32]uint32)
+				p.memberDecorations[key] = decorations
+			}
+			decorations[operands[2]] = literal(operands[3:])
+		}
+	}
+
+	return nil
+}
+
+// specConstantList reflects the specialization constants. Those without a
+// SpecId are computed from others and can't be set.
+func (p *parser) specConstantList() ([]SpecConstant, error) {
+	var constants []SpecConstant
+	for id, typeID := range p.specConstants {
+		specID, ok := p.decorated(id, decorationSpecID)
+		if !ok {
+			continue
+		}
+
+		constant := SpecConstant{Name: p.names[id], ID: specID}
+		t, err := p.typ(typeID)
+		if err != nil {
+			return nil, err
+		}
+		switch t.op {
+		case opTypeBool:
+			constant.Type, constant.Size = ScalarBool, 4
+		case opTypeFloat:
+			constant.Type, constant.Size = ScalarFloat, t.operands[0]/8
+		case opTypeInt:
+			constant.Type, constant.Size = ScalarUint, t.operands[0]/8
+			if t.operands[1] != 0 {
+				constant.Type = ScalarInt
+			}
+		default:
+			return nil, fmt.Errorf("%w: specialization constant %d has type instruction %d", ErrInvalidModule, specID, t.op)
+		}
+		constants = append(constants, constant)
+	}
+
+	sort.Slice(constants, func(i, j int) bool { return constants[i].ID < constants[j].ID })
+	return constants, nil
+}
+
+func (p *parser) decorate(id uint32, operands []uint32) {
+	if p.decorations[id] == nil {
+		p.decorations[id] = make(map[uint32]uint32)
+	}
+	p.decorations[id][operands[0]] = literal(operands[1:])
+}
+
+// literal returns the first literal operand of a decoration, 0 for those
+// without one.
+func literal(operands []uint32) uint32 {
+	if len(operands) == 0 {
+		return 0
+	}
+	return operands[0]
+}
+
+// literalString decodes a nul-terminated UTF-8 string packed little-endian
+// into words, returning it and the number of words it takes.
+func literalString(words []uint32) (string, int) {
+	var b []byte
+	for i, word := range words {
+		for shift := 0; shift < 32; shift += 8 {
+			c := byte(word >> shift)
+			if c == 0 {
+				return string(b), i + 1
+			}
+			b = append(b, c)
+		}
+	}
+	return string(b), len(words)
+}
+
+func (p *parser) decorated(id, decoration uint32) (uint32, bool) {
+	value, ok := p.decorations[id][decoration]
+	return value, ok
+}
+
+func (p *parser) memberDecorated(id, member, decoration uint32) (uint32, bool) {
+	value, ok := p.memberDecorations[memberKey{id, member}][decoration]
+	return value, ok
+}
+
+func (p *parser) typ(id uint32) (typeInfo, error) {
+	t, ok := p.types[id]
+	if !ok {
+		return typeInfo{}, fmt.Errorf("%w: %%%d is not a type", ErrInvalidModule, id)
+	}
+	return t, nil
+}
+
+// nestedType returns the type depth levels into the type of a variable,
+// failing past maxTypeDepth so a cyclic type can't be followed forever.
+func (p *parser) nestedType(id uint32, depth int) (typeInfo, error) {
+	if depth > maxTypeDepth {
+		return typeInfo{}, fmt.Errorf("%w: type %%%d nests more than %d deep", ErrInvalidModule, id, maxTypeDepth)
+	}
+	return p.typ(id)
+}
+
+// pointee returns the type a pointer type points to.
+func (p *parser) pointee(id uint32) (uint32, error) {
+	t, err := p.typ(id)
+	if err != nil {
+		return 0, err
+	}
+	if t.op != opTypePointer {
+		return 0, fmt.Errorf("%w: variable type %%%d is not a pointer", ErrInvalidModule, id)
+	}
+	return t.operands[1], nil
+}
+
+func (p *parser) arrayLength(t typeInfo) (uint32, error) {
+	length, ok := p.constants[t.operands[1]]
+	if !ok {
+		return 0, fmt.Errorf("%w: array length %%%d is not a constant", ErrInvalidModule, t.operands[1])
+	}
+	return length, nil
+}
+
+func (p *parser) reflect(ep entryPoint, version uint32) (EntryPoint, error) {
+	stage, ok := executionModelStages[ep.model]
+	if !ok {
+		return EntryPoint{}, fmt.Errorf("unsupported execution model %d", ep.model)
+	}
+	entry := EntryPoint{Name: ep.name, Stage: stage}
+
+	resources := ep.interfaceIDs
+	if version < version14 {
+		resources = p.globals
+	}
+
+	for _, id := range ep.interfaceIDs {
+		v, ok := p.variables[id]
+		if !ok || (v.storage != storageInput && v.storage != storageOutput) {
+			continue
+		}
+
+		variables, err := p.interfaceVariables(v, stage)
+		if err != nil {
+			return EntryPoint{}, err
+		}
+		if v.storage == storageInput {
+			entry.Inputs = append(entry.Inputs, variables...)
+		} else {
+			entry.Outputs = append(entry.Outputs, variables...)
+		}
+	}
+
+	for _, id := range resources {
+		v, ok := p.variables[id]
+		if !ok {
+			continue
+		}
+
+		switch v.storage {
+		case storageUniformConstant, storageUniform, storageStorageBuffer:
+			binding, ok, err := p.binding(v)
+			if err != nil {
+				return EntryPoint{}, err
+			}
+			if ok {
+				entry.Bindings = append(entry.Bindings, binding)
+			}
+		case storagePushConstant:
+			pushConstants, err := p.pushConstants(v)
+			if err != nil {
+				return EntryPoint{}, err
+			}
+			entry.PushConstants = append(entry.PushConstants, pushConstants)
+		}
+	}
+
+	sort.Slice(entry.Inputs, func(i, j int) bool { return entry.Inputs[i].Location < entry.Inputs[j].Location })
+	sort.Slice(entry.Outputs, func(i, j int) bool { return entry.Outputs[i].Location < entry.Outputs[j].Location })
+	sort.Slice(entry.Bindings, func(i, j int) bool {
+		a, b := entry.Bindings[i], entry.Bindings[j]
+		return a.Set < b.Set || a.Set == b.Set && a.Binding < b.Binding
+	})
+
+	return entry, nil
+}
+
+// perVertex reports whether the inputs, or outputs, of stage are arrays with
+// an element per vertex.
+func perVertex(stage vk.ShaderStageFlagBits, storage uint32) bool {
+	switch stage {
+	case vk.ShaderStageTessellationControlBit:
+		return true
+	case vk.ShaderStageTessellationEvaluationBit, vk.ShaderStageGeometryBit:
+		return storage == storageInput
+	}
+	return false
+}
+
+// interfaceVariables reflects an input or output variable. Blocks, such as
+// out Block { ... } v, are reflected member by member, the members take
+// consecutive locations from that of the variable unless they have their
+// own. Built-ins return nothing.
+func (p *parser) interfaceVariables(v variable, stage vk.ShaderStageFlagBits) ([]Variable, error) {
+	typeID, err := p.pointee(v.typeID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := p.typ(typeID)
+	if err != nil {
+		return nil, err
+	}
+	if perVertex(stage, v.storage) && t.op == opTypeArray {
+		typeID = t.operands[0]
+		if t, err = p.typ(typeID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := p.decorated(v.id, decorationBuiltIn); ok {
+		return nil, nil
+	}
+	// Built-in blocks such as gl_PerVertex decorate their members instead.
+	if t.op == opTypeStruct {
+		if _, ok := p.memberDecorated(typeID, 0, decorationBuiltIn); ok {
+			return nil, nil
+		}
+	}
+
+	name := p.names[v.id]
+	location, hasLocation := p.decorated(v.id, decorationLocation)
+	if t.op != opTypeStruct {
+		if !hasLocation {
+			return nil, fmt.Errorf("%w: %s %q has no location", ErrInvalidModule, storageName(v.storage), name)
+		}
+		variable, err := p.locationVariable(name, location, typeID)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %w", storageName(v.storage), name, err)
+		}
+		return []Variable{variable}, nil
+	}
+
+	variables := make([]Variable, 0, len(t.operands))
+	for member, memberType := range t.operands {
+		memberName := p.memberNames[memberKey{typeID, uint32(member)}]
+		if name != "" {
+			memberName = name + "." + memberName
+		}
+		if memberLocation, ok := p.memberDecorated(typeID, uint32(member), decorationLocation); ok {
+			location, hasLocation = memberLocation, true
+		}
+		if !hasLocation {
+			return nil, fmt.Errorf("%w: %s %q has no location", ErrInvalidModule, storageName(v.storage), memberName)
+		}
+
+		variable, err := p.locationVariable(memberName, location, memberType)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %w", storageName(v.storage), memberName, err)
+		}
+		variables = append(variables, variable)
+		location += variable.Locations
+	}
+
+	return variables, nil
+}
+
+// locationVariable reflects a scalar, vector, matrix or array of those
+// starting at location.
+func (p *parser) locationVariable(name string, location, typeID uint32) (Variable, error) {
+	variable := Variable{Name: name, Location: location, Locations: 1}
+	for depth := 0; ; depth++ {
+		t, err := p.nestedType(typeID, depth)
+		if err != nil {
+			return Variable{}, err
+		}
+
+		switch t.op {
+		case opTypeArray:
+			length, err := p.arrayLength(t)
+			if err != nil {
+				return Variable{}, err
+			}
+			variable.Locations *= length
+		case opTypeMatrix:
+			variable.Locations *= t.operands[1]
+		default:
+			err := p.scalar(t, &variable)
+			if err != nil {
+				return Variable{}, err
+			}
+			variable.Format = attributeFormat(variable.Type, variable.Width, variable.Components)
+			return variable, nil
+		}
+		typeID = t.operands[0]
+	}
+}
+
+// scalar fills in the component type, width and count of a scalar or vector.
+func (p *parser) scalar(t typeInfo, v *Variable) error {
+	v.Components = 1
+	if t.op == opTypeVector {
+		v.Components = t.operands[1]
+		var err error
+		if t, err = p.typ(t.operands[0]); err != nil {
+			return err
+		}
+	}
+
+	switch t.op {
+	case opTypeFloat:
+		v.Type, v.Width = ScalarFloat, t.operands[0]
+	case opTypeInt:
+		v.Type, v.Width = ScalarUint, t.operands[0]
+		if t.operands[1] != 0 {
+			v.Type = ScalarInt
+		}
+	default:
+		return fmt.Errorf("unsupported interface type, instruction %d", t.op)
+	}
+	return nil
+}
+
+// binding reflects a resource variable. Variables without a descriptor set
+// and binding, such as acceleration structures bound some other way, are
+// skipped.
+func (p *parser) binding(v variable) (Binding, bool, error) {
+	set, hasSet := p.decorated(v.id, decorationDescriptorSet)
+	index, hasBinding := p.decorated(v.id, decorationBinding)
+	if !hasSet && !hasBinding {
+		return Binding{}, false, nil
+	}
+
+	binding := Binding{Name: p.names[v.id], Set: set, Binding: index, Count: 1}
+	typeID, err := p.pointee(v.typeID)
+	if err != nil {
+		return Binding{}, false, err
+	}
+
+	for depth := 0; ; depth++ {
+		t, err := p.nestedType(typeID, depth)
+		if err != nil {
+			return Binding{}, false, err
+		}
+
+		switch t.op {
+		case opTypeArray:
+			length, err := p.arrayLength(t)
+			if err != nil {
+				return Binding{}, false, err
+			}
+			binding.Count *= length
+			typeID = t.operands[0]
+			continue
+		case opTypeRuntimeArray:
+			binding.Count = 0
+			typeID = t.operands[0]
+			continue
+		case opTypeSampler:
+			binding.Type = vk.DescriptorTypeSampler
+		case opTypeSampledImage:
+			binding.Type = vk.DescriptorTypeCombinedImageSampler
+		case opTypeImage:
+			binding.Type = imageDescriptorType(t)
+		case opTypeStruct:
+			switch {
+			case v.storage == storageStorageBuffer:
+				binding.Type = vk.DescriptorTypeStorageBuffer
+			case p.hasDecoration(typeID, decorationBufferBlock):
+				binding.Type = vk.DescriptorTypeStorageBuffer
+			case p.hasDecoration(typeID, decorationBlock):
+				binding.Type = vk.DescriptorTypeUniformBuffer
+			default:
+				return Binding{}, false, fmt.Errorf("%w: buffer %q is not a block", ErrInvalidModule, binding.Name)
+			}
+		default:
+			return Binding{}, false, fmt.Errorf("resource %q has unsupported type, instruction %d", binding.Name, t.op)
+		}
+
+		return binding, true, nil
+	}
+}
+
+func (p *parser) hasDecoration(id, decoration uint32) bool {
+	_, ok := p.decorated(id, decoration)
+	return ok
+}
+
+func imageDescriptorType(t typeInfo) vk.DescriptorType {
+	// Operands: sampled type, dim, depth, arrayed, multisampled, sampled,
+	// format. Sampled is 2 for storage images.
+	dim, sampled := t.operands[1], t.operands[5]
+	switch {
+	case dim == dimSubpassData:
+		return vk.DescriptorTypeInputAttachment
+	case dim == dimBuffer && sampled == 2:
+		return vk.DescriptorTypeStorageTexelBuffer
+	case dim == dimBuffer:
+		return vk.DescriptorTypeUniformTexelBuffer
+	case sampled == 2:
+		return vk.DescriptorTypeStorageImage
+	default:
+		return vk.DescriptorTypeSampledImage
+	}
+}
+
+func (p *parser) pushConstants(v variable) (PushConstantRange, error) {
+	name := p.names[v.id]
+	typeID, err := p.pointee(v.typeID)
+	if err != nil {
+		return PushConstantRange{}, err
+	}
+	t, err := p.typ(typeID)
+	if err != nil {
+		return PushConstantRange{}, err
+	}
+	if t.op != opTypeStruct || len(t.operands) == 0 {
+		return PushConstantRange{}, fmt.Errorf("%w: push constants %q are not a block", ErrInvalidModule, name)
+	}
+
+	start, end := ^uint32(0), uint32(0)
+	for member, memberType := range t.operands {
+		offset, ok := p.memberDecorated(typeID, uint32(member), decorationOffset)
+		if !ok {
+			return PushConstantRange{}, fmt.Errorf("%w: member %d of push constants %q has no offset", ErrInvalidModule, member, name)
+		}
+		matrixStride, _ := p.memberDecorated(typeID, uint32(member), decorationMatrixStride)
+		size, err := p.size(memberType, matrixStride, 1)
+		if err != nil {
+			return PushConstantRange{}, fmt.Errorf("push constants %q: %w", name, err)
+		}
+
+		if offset < start {
+			start = offset
+		}
+		if offset+size > end {
+			end = offset + size
+		}
+	}
+
+	return PushConstantRange{Name: name, Offset: start, Size: end - start}, nil
+}
+
+// size returns the size in bytes of a type laid out in a block.
+// matrixStride is the MatrixStride of the member the type belongs to, depth
+// how deeply the type is nested.
+func (p *parser) size(id, matrixStride uint32, depth int) (uint32, error) {
+	t, err := p.nestedType(id, depth)
+	if err != nil {
+		return 0, err
+	}
+
+	switch t.op {
+	case opTypeInt, opTypeFloat:
+		return t.operands[0] / 8, nil
+	case opTypeVector:
+		size, err := p.size(t.operands[0], 0, depth+1)
+		return t.operands[1] * size, err
+	case opTypeMatrix:
+		if matrixStride != 0 {
+			return t.operands[1] * matrixStride, nil
+		}
+		size, err := p.size(t.operands[0], 0, depth+1)
+		return t.operands[1] * size, err
+	case opTypeArray:
+		length, err := p.arrayLength(t)
+		if err != nil {
+			return 0, err
+		}
+		if stride, ok := p.decorated(id, decorationArrayStride); ok {
+			return length * stride, nil
+		}
+		size, err := p.size(t.operands[0], matrixStride, depth+1)
+		return length * size, err
+	case opTypeRuntimeArray:
+		return 0, nil
+	case opTypeStruct:
+		var size uint32
+		for member, memberType := range t.operands {
+			offset, _ := p.memberDecorated(id, uint32(member), decorationOffset)
+			stride, _ := p.memberDecorated(id, uint32(member), decorationMatrixStride)
+			memberSize, err := p.size(memberType, stride, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			if offset+memberSize > size {
+				size = offset + memberSize
+			}
+		}
+		return size, nil
+	default:
+		return 0, fmt.Errorf("%w: type %%%d, instruction %d, has no size in a block", ErrInvalidModule, id, t.op)
+	}
+}
+
+func storageName(storage uint32) string {
+	if storage == storageInput {
+		return "input"
+	}
+	return "output"
+}
@@ -0,0 +1,248 @@
+package spirv
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"vulkan-tutorial-go/16-swap-chain-recreation/shaders"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// assemble builds a SPIR-V 1.0 module from instructions, each its opcode
+// followed by its operands.
+func assemble(instructions ...[]uint32) []uint32 {
+	words := []uint32{magic, 0x00010000, 0, 100, 0}
+	for _, instruction := range instructions {
+		words = append(words, uint32(len(instruction))<<16|instruction[0])
+		words = append(words, instruction[1:]...)
+	}
+	return words
+}
+
+func op(code uint32, operands ...uint32) []uint32 {
+	return append([]uint32{code}, operands...)
+}
+
+// str packs s into nul-terminated little-endian words.
+func str(s string) []uint32 {
+	b := append([]byte(s), make([]byte, 4-len(s)%4)...)
+	words := make([]uint32, len(b)/4)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+	return words
+}
+
+func entry(model, id uint32, interfaceIDs ...uint32) []uint32 {
+	operands := append([]uint32{model, id}, str("main")...)
+	return op(opEntryPoint, append(operands, interfaceIDs...)...)
+}
+
+func TestParseShaders(t *testing.T) {
+	tests := []struct {
+		file    string
+		stage   vk.ShaderStageFlagBits
+		inputs  []Variable
+		outputs []Variable
+	}{
+		{
+			file:    "vert.spv",
+			stage:   vk.ShaderStageVertexBit,
+			outputs: []Variable{{Name: "fragColor", Locations: 1, Width: 32, Components: 3, Format: vk.FormatR32g32b32Sfloat}},
+		},
+		{
+			file:    "frag.spv",
+			stage:   vk.ShaderStageFragmentBit,
+			inputs:  []Variable{{Name: "fragColor", Locations: 1, Width: 32, Components: 3, Format: vk.FormatR32g32b32Sfloat}},
+			outputs: []Variable{{Name: "outColor", Locations: 1, Width: 32, Components: 4, Format: vk.FormatR32g32b32a32Sfloat}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			code, err := shaders.FS.ReadFile(test.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			words := make([]uint32, len(code)/4)
+			for i := range words {
+				words[i] = binary.LittleEndian.Uint32(code[i*4:])
+			}
+
+			module, err := Parse(words)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(module.EntryPoints) != 1 {
+				t.Fatalf("got %d entry points, want 1", len(module.EntryPoints))
+			}
+			entry, ok := module.EntryPoint("main", test.stage)
+			if !ok {
+				t.Fatalf("no main entry point for stage %d", test.stage)
+			}
+			if !reflect.DeepEqual(entry.Inputs, test.inputs) {
+				t.Errorf("got inputs %+v, want %+v", entry.Inputs, test.inputs)
+			}
+			if !reflect.DeepEqual(entry.Outputs, test.outputs) {
+				t.Errorf("got outputs %+v, want %+v", entry.Outputs, test.outputs)
+			}
+		})
+	}
+}
+
+func TestParseInterfaceBlock(t *testing.T) {
+	// in Block { vec3 color; vec2 uv; } v; with the locations given by
+	// decorations.
+	block := func(decorations ...[]uint32) []uint32 {
+		return assemble(append([][]uint32{
+			entry(4, 1, 25),
+			op(opName, append([]uint32{25}, str("v")...)...),
+			op(opMemberName, append([]uint32{23, 0}, str("color")...)...),
+			op(opMemberName, append([]uint32{23, 1}, str("uv")...)...),
+			op(opDecorate, 23, decorationBlock),
+			op(opTypeFloat, 20, 32),
+			op(opTypeVector, 21, 20, 3),
+			op(opTypeVector, 22, 20, 2),
+			op(opTypeStruct, 23, 21, 22),
+			op(opTypePointer, 24, storageInput, 23),
+			op(opVariable, 24, 25, storageInput),
+		}, decorations...)...)
+	}
+	color := Variable{Name: "v.color", Locations: 1, Width: 32, Components: 3, Format: vk.FormatR32g32b32Sfloat}
+	uv := Variable{Name: "v.uv", Locations: 1, Width: 32, Components: 2, Format: vk.FormatR32g32Sfloat}
+	at := func(v Variable, location uint32) Variable {
+		v.Location = location
+		return v
+	}
+
+	tests := []struct {
+		name   string
+		words  []uint32
+		inputs []Variable
+	}{
+		{
+			name:   "variable location",
+			words:  block(op(opDecorate, 25, decorationLocation, 2)),
+			inputs: []Variable{at(color, 2), at(uv, 3)},
+		},
+		{
+			name: "member locations",
+			words: block(
+				op(opMemberDecorate, 23, 0, decorationLocation, 5),
+				op(opMemberDecorate, 23, 1, decorationLocation, 1),
+			),
+			inputs: []Variable{at(uv, 1), at(color, 5)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module, err := Parse(test.words)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inputs := module.EntryPoints[0].Inputs
+			if !reflect.DeepEqual(inputs, test.inputs) {
+				t.Errorf("got inputs %+v, want %+v", inputs, test.inputs)
+			}
+		})
+	}
+
+	_, err := Parse(block())
+	if !errors.Is(err, ErrInvalidModule) {
+		t.Errorf("block without locations: got error %v, want ErrInvalidModule", err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	named := assemble(op(opName, append([]uint32{10}, str("color")...)...))
+	uint4 := [][]uint32{
+		op(opTypeInt, 2, 32, 0),
+		op(opConstant, 2, 3, 4),
+	}
+
+	tests := []struct {
+		name  string
+		words []uint32
+	}{
+		{"truncated header", named[:3]},
+		{"truncated instruction", named[:len(named)-1]},
+		{"bad magic", append([]uint32{0xdeadbeef}, named[1:]...)},
+		{"cyclic output", assemble(append(uint4,
+			entry(0, 1, 10),
+			op(opDecorate, 10, decorationLocation, 0),
+			op(opTypeArray, 4, 4, 3),
+			op(opTypePointer, 5, storageOutput, 4),
+			op(opVariable, 5, 10, storageOutput),
+		)...)},
+		{"cyclic binding", assemble(append(uint4,
+			entry(0, 1),
+			op(opDecorate, 10, decorationDescriptorSet, 0),
+			op(opDecorate, 10, decorationBinding, 0),
+			op(opTypeArray, 4, 4, 3),
+			op(opTypePointer, 5, storageUniformConstant, 4),
+			op(opVariable, 5, 10, storageUniformConstant),
+		)...)},
+		{"cyclic push constants", assemble(
+			entry(0, 1),
+			op(opMemberDecorate, 6, 0, decorationOffset, 0),
+			op(opTypeStruct, 6, 6),
+			op(opTypePointer, 7, storagePushConstant, 6),
+			op(opVariable, 7, 10, storagePushConstant),
+		)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.words)
+			if !errors.Is(err, ErrInvalidModule) {
+				t.Errorf("got error %v, want ErrInvalidModule", err)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	header := []uint32{magic, 0x00010000, 0, 8, 0}
+	encode := func(order binary.ByteOrder, words []uint32) []byte {
+		code := make([]byte, len(words)*4)
+		for i, word := range words {
+			order.PutUint32(code[i*4:], word)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name  string
+		code  []byte
+		words []uint32
+		err   bool
+	}{
+		{"little endian", encode(binary.LittleEndian, header), header, false},
+		{"big endian", encode(binary.BigEndian, header), header, false},
+		{"truncated word", encode(binary.LittleEndian, header)[:19], nil, true},
+		{"empty", nil, nil, true},
+		{"short header", encode(binary.LittleEndian, header[:4]), nil, true},
+		{"bad magic", encode(binary.LittleEndian, []uint32{0xdeadbeef, 0x00010000, 0, 8, 0}), nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			words, err := Decode(test.code)
+			if test.err {
+				if !errors.Is(err, ErrInvalidModule) {
+					t.Fatalf("got error %v, want ErrInvalidModule", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(words, test.words) {
+				t.Errorf("got %#x, want %#x", words, test.words)
+			}
+		})
+	}
+}
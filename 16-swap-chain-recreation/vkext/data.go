@@ -0,0 +1,37 @@
+package vkext
+
+/*
+#include <stdlib.h>
+#include <string.h>
+*/
+import "C"
+
+import "unsafe"
+
+// Data is a copy of Go memory in C memory, for the untyped pointers of
+// structures passed to Vulkan, such as vk.SpecializationInfo.PData. cgo
+// doesn't let Vulkan keep pointers to Go memory.
+type Data struct {
+	data unsafe.Pointer
+}
+
+// NewData copies size bytes at data into C memory.
+func NewData(data unsafe.Pointer, size uintptr) *Data {
+	// At least one byte, malloc(0) may return nil.
+	p := C.malloc(C.size_t(size + 1))
+	C.memcpy(p, data, C.size_t(size))
+	return &Data{data: p}
+}
+
+func (d *Data) Ref() unsafe.Pointer {
+	return d.data
+}
+
+// Free frees the copy. It does nothing on a nil *Data.
+func (d *Data) Free() {
+	if d == nil {
+		return
+	}
+	C.free(d.data)
+	d.data = nil
+}
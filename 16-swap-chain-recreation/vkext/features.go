@@ -0,0 +1,361 @@
+package vkext
+
+/*
+#include <stdlib.h>
+#include "vkext.h"
+*/
+import "C"
+
+import (
+	"reflect"
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+const (
+	structureTypePhysicalDeviceFeatures2                 = 1000059000
+	structureTypePhysicalDeviceVulkan11Features          = 49
+	structureTypePhysicalDeviceVulkan12Features          = 51
+	structureTypePhysicalDeviceVulkan13Features          = 53
+	structureTypePhysicalDeviceTimelineSemaphoreFeatures = 1000207000
+	structureTypePhysicalDeviceDynamicRenderingFeatures  = 1000044003
+	structureTypePhysicalDeviceSynchronization2Features  = 1000314007
+	structureTypePhysicalDevicePortabilitySubsetFeatures = 1000163000
+)
+
+// Vulkan11Features is VkPhysicalDeviceVulkan11Features, the features added
+// by Vulkan 1.1. It can only be queried and enabled on Vulkan 1.2 devices.
+type Vulkan11Features struct {
+	StorageBuffer16BitAccess           vk.Bool32
+	UniformAndStorageBuffer16BitAccess vk.Bool32
+	StoragePushConstant16              vk.Bool32
+	StorageInputOutput16               vk.Bool32
+	Multiview                          vk.Bool32
+	MultiviewGeometryShader            vk.Bool32
+	MultiviewTessellationShader        vk.Bool32
+	VariablePointersStorageBuffer      vk.Bool32
+	VariablePointers                   vk.Bool32
+	ProtectedMemory                    vk.Bool32
+	SamplerYcbcrConversion             vk.Bool32
+	ShaderDrawParameters               vk.Bool32
+}
+
+// Vulkan12Features is VkPhysicalDeviceVulkan12Features, the features added
+// by Vulkan 1.2.
+type Vulkan12Features struct {
+	SamplerMirrorClampToEdge                           vk.Bool32
+	DrawIndirectCount                                  vk.Bool32
+	StorageBuffer8BitAccess                            vk.Bool32
+	UniformAndStorageBuffer8BitAccess                  vk.Bool32
+	StoragePushConstant8                               vk.Bool32
+	ShaderBufferInt64Atomics                           vk.Bool32
+	ShaderSharedInt64Atomics                           vk.Bool32
+	ShaderFloat16                                      vk.Bool32
+	ShaderInt8                                         vk.Bool32
+	DescriptorIndexing                                 vk.Bool32
+	ShaderInputAttachmentArrayDynamicIndexing          vk.Bool32
+	ShaderUniformTexelBufferArrayDynamicIndexing       vk.Bool32
+	ShaderStorageTexelBufferArrayDynamicIndexing       vk.Bool32
+	ShaderUniformBufferArrayNonUniformIndexing         vk.Bool32
+	ShaderSampledImageArrayNonUniformIndexing          vk.Bool32
+	ShaderStorageBufferArrayNonUniformIndexing         vk.Bool32
+	ShaderStorageImageArrayNonUniformIndexing          vk.Bool32
+	ShaderInputAttachmentArrayNonUniformIndexing       vk.Bool32
+	ShaderUniformTexelBufferArrayNonUniformIndexing    vk.Bool32
+	ShaderStorageTexelBufferArrayNonUniformIndexing    vk.Bool32
+	DescriptorBindingUniformBufferUpdateAfterBind      vk.Bool32
+	DescriptorBindingSampledImageUpdateAfterBind       vk.Bool32
+	DescriptorBindingStorageImageUpdateAfterBind       vk.Bool32
+	DescriptorBindingStorageBufferUpdateAfterBind      vk.Bool32
+	DescriptorBindingUniformTexelBufferUpdateAfterBind vk.Bool32
+	DescriptorBindingStorageTexelBufferUpdateAfterBind vk.Bool32
+	DescriptorBindingUpdateUnusedWhilePending          vk.Bool32
+	DescriptorBindingPartiallyBound                    vk.Bool32
+	DescriptorBindingVariableDescriptorCount           vk.Bool32
+	RuntimeDescriptorArray                             vk.Bool32
+	SamplerFilterMinmax                                vk.Bool32
+	ScalarBlockLayout                                  vk.Bool32
+	ImagelessFramebuffer                               vk.Bool32
+	UniformBufferStandardLayout                        vk.Bool32
+	ShaderSubgroupExtendedTypes                        vk.Bool32
+	SeparateDepthStencilLayouts                        vk.Bool32
+	HostQueryReset                                     vk.Bool32
+	// TimelineSemaphore is also available on Vulkan 1.1 devices with
+	// VK_KHR_timeline_semaphore.
+	TimelineSemaphore                             vk.Bool32
+	BufferDeviceAddress                           vk.Bool32
+	BufferDeviceAddressCaptureReplay              vk.Bool32
+	BufferDeviceAddressMultiDevice                vk.Bool32
+	VulkanMemoryModel                             vk.Bool32
+	VulkanMemoryModelDeviceScope                  vk.Bool32
+	VulkanMemoryModelAvailabilityVisibilityChains vk.Bool32
+	ShaderOutputViewportIndex                     vk.Bool32
+	ShaderOutputLayer                             vk.Bool32
+	SubgroupBroadcastDynamicId                    vk.Bool32
+}
+
+// Vulkan13Features is VkPhysicalDeviceVulkan13Features, the features added
+// by Vulkan 1.3.
+type Vulkan13Features struct {
+	RobustImageAccess                                  vk.Bool32
+	InlineUniformBlock                                 vk.Bool32
+	DescriptorBindingInlineUniformBlockUpdateAfterBind vk.Bool32
+	PipelineCreationCacheControl                       vk.Bool32
+	PrivateData                                        vk.Bool32
+	ShaderDemoteToHelperInvocation                     vk.Bool32
+	ShaderTerminateInvocation                          vk.Bool32
+	SubgroupSizeControl                                vk.Bool32
+	ComputeFullSubgroups                               vk.Bool32
+	// Synchronization2 is also available on Vulkan 1.2 devices with
+	// VK_KHR_synchronization2.
+	Synchronization2                    vk.Bool32
+	TextureCompressionASTC_HDR          vk.Bool32
+	ShaderZeroInitializeWorkgroupMemory vk.Bool32
+	// DynamicRendering is also available on Vulkan 1.2 devices with
+	// VK_KHR_dynamic_rendering.
+	DynamicRendering        vk.Bool32
+	ShaderIntegerDotProduct vk.Bool32
+	Maintenance4            vk.Bool32
+}
+
+// Features are the features of Vulkan 1.1 to 1.3, on top of those in
+// vk.PhysicalDeviceFeatures.
+type Features struct {
+	Vulkan11 Vulkan11Features
+	Vulkan12 Vulkan12Features
+	Vulkan13 Vulkan13Features
+}
+
+// PortabilitySubsetFeatures is VkPhysicalDevicePortabilitySubsetFeaturesKHR,
+// the parts of Vulkan a VK_KHR_portability_subset implementation, such as
+// MoltenVK, may not support.
+type PortabilitySubsetFeatures struct {
+	ConstantAlphaColorBlendFactors         vk.Bool32
+	Events                                 vk.Bool32
+	ImageViewFormatReinterpretation        vk.Bool32
+	ImageViewFormatSwizzle                 vk.Bool32
+	ImageView2DOn3DImage                   vk.Bool32
+	MultisampleArrayImage                  vk.Bool32
+	MutableComparisonSamplers              vk.Bool32
+	PointPolygons                          vk.Bool32
+	SamplerMipLodBias                      vk.Bool32
+	SeparateStencilMaskRef                 vk.Bool32
+	ShaderSampleRateInterpolationFunctions vk.Bool32
+	TessellationIsolines                   vk.Bool32
+	TessellationPointMode                  vk.Bool32
+	TriangleFans                           vk.Bool32
+	VertexAttributeAccessBeyondStride      vk.Bool32
+}
+
+// promotedFeatures are features that became part of a VulkanXXFeatures
+// structure, but that older devices offer through an extension with a
+// feature structure of its own.
+var promotedFeatures = []struct {
+	extension string
+	sType     C.VkStructureType
+	// version is the Vulkan version the feature was promoted to, minVersion
+	// the oldest one the extension is used on.
+	version, minVersion uint32
+	feature             func(*Features) *vk.Bool32
+}{
+	{
+		extension:  timelineSemaphoreExtension,
+		sType:      structureTypePhysicalDeviceTimelineSemaphoreFeatures,
+		version:    version12,
+		minVersion: version11,
+		feature:    func(f *Features) *vk.Bool32 { return &f.Vulkan12.TimelineSemaphore },
+	},
+	{
+		extension:  synchronization2Extension,
+		sType:      structureTypePhysicalDeviceSynchronization2Features,
+		version:    version13,
+		minVersion: version12,
+		feature:    func(f *Features) *vk.Bool32 { return &f.Vulkan13.Synchronization2 },
+	},
+	{
+		// Its dependencies, VK_KHR_depth_stencil_resolve and
+		// VK_KHR_create_renderpass2, are part of Vulkan 1.2.
+		extension:  dynamicRenderingExtension,
+		sType:      structureTypePhysicalDeviceDynamicRenderingFeatures,
+		version:    version13,
+		minVersion: version12,
+		feature:    func(f *Features) *vk.Bool32 { return &f.Vulkan13.DynamicRendering },
+	},
+}
+
+// featureChain is a pNext chain of feature structures in C memory, each
+// paired with the Go features it is read into or filled from.
+type featureChain struct {
+	structs []*C.vkextFeatures
+	values  [][]*vk.Bool32
+}
+
+// add appends a feature structure of sType for the vk.Bool32 fields of
+// value, a pointer to a struct of them or to a single one.
+func (c *featureChain) add(sType C.VkStructureType, value interface{}) {
+	s := (*C.vkextFeatures)(C.calloc(1, C.sizeof_vkextFeatures))
+	s.sType = sType
+	if n := len(c.structs); n > 0 {
+		c.structs[n-1].pNext = unsafe.Pointer(s)
+	}
+	c.structs = append(c.structs, s)
+	c.values = append(c.values, bools(value))
+}
+
+// build adds the structures that hold the features of a device with
+// apiVersion, using extensions for the promoted features of newer versions.
+func (c *featureChain) build(features *Features, apiVersion uint32, extensions func(name string) bool) {
+	if apiVersion >= version12 {
+		c.add(structureTypePhysicalDeviceVulkan11Features, &features.Vulkan11)
+		c.add(structureTypePhysicalDeviceVulkan12Features, &features.Vulkan12)
+	}
+	if apiVersion >= version13 {
+		c.add(structureTypePhysicalDeviceVulkan13Features, &features.Vulkan13)
+	}
+	for _, promoted := range promotedFeatures {
+		if apiVersion < promoted.version && apiVersion >= promoted.minVersion && extensions(promoted.extension) {
+			c.add(promoted.sType, promoted.feature(features))
+		}
+	}
+}
+
+func (c *featureChain) head() unsafe.Pointer {
+	if len(c.structs) == 0 {
+		return nil
+	}
+	return unsafe.Pointer(c.structs[0])
+}
+
+// store copies the Go features into the structures.
+func (c *featureChain) store() {
+	for i, s := range c.structs {
+		for j, value := range c.values[i] {
+			s.features[j] = C.VkBool32(*value)
+		}
+	}
+}
+
+// load copies the structures into the Go features.
+func (c *featureChain) load() {
+	for i, s := range c.structs {
+		for j, value := range c.values[i] {
+			*value = vk.Bool32(s.features[j])
+		}
+	}
+}
+
+func (c *featureChain) free() {
+	for _, s := range c.structs {
+		C.free(unsafe.Pointer(s))
+	}
+	c.structs, c.values = nil, nil
+}
+
+// bools returns pointers to the vk.Bool32 fields of value, in order.
+func bools(value interface{}) []*vk.Bool32 {
+	if b, ok := value.(*vk.Bool32); ok {
+		return []*vk.Bool32{b}
+	}
+
+	v := reflect.ValueOf(value).Elem()
+	fields := make([]*vk.Bool32, v.NumField())
+	for i := range fields {
+		fields[i] = v.Field(i).Addr().Interface().(*vk.Bool32)
+	}
+	return fields
+}
+
+// PhysicalDeviceFeatures returns the features of physicalDevice, whose
+// Vulkan version, capped at that of the instance, is apiVersion and which
+// supports extensions. Features of versions newer than apiVersion are
+// reported only when an extension provides them, see the field comments.
+// Without vkGetPhysicalDeviceFeatures2 every feature is reported missing.
+func (i *Instance) PhysicalDeviceFeatures(physicalDevice vk.PhysicalDevice, apiVersion uint32, extensions map[string]bool) Features {
+	var features Features
+	if !i.hasFeatures2(apiVersion) {
+		return features
+	}
+
+	var chain featureChain
+	defer chain.free()
+	chain.build(&features, apiVersion, func(name string) bool { return extensions[name] })
+	i.getFeatures2(physicalDevice, &chain)
+
+	return features
+}
+
+// PortabilitySubsetFeatures returns the VK_KHR_portability_subset features of
+// physicalDevice, which must advertise the extension, and whose Vulkan
+// version, capped at that of the instance, is apiVersion. ok is false when
+// vkGetPhysicalDeviceFeatures2 is unavailable.
+func (i *Instance) PortabilitySubsetFeatures(physicalDevice vk.PhysicalDevice, apiVersion uint32) (features PortabilitySubsetFeatures, ok bool) {
+	if !i.hasFeatures2(apiVersion) {
+		return features, false
+	}
+
+	var chain featureChain
+	defer chain.free()
+	chain.add(structureTypePhysicalDevicePortabilitySubsetFeatures, &features)
+	i.getFeatures2(physicalDevice, &chain)
+
+	return features, true
+}
+
+// hasFeatures2 reports whether vkGetPhysicalDeviceFeatures2 can be called on
+// a device with apiVersion.
+func (i *Instance) hasFeatures2(apiVersion uint32) bool {
+	return i.getPhysicalDeviceFeatures2 != nil && (apiVersion >= version11 || i.properties2)
+}
+
+// getFeatures2 calls vkGetPhysicalDeviceFeatures2 with chain and loads the
+// result into its Go features.
+func (i *Instance) getFeatures2(physicalDevice vk.PhysicalDevice, chain *featureChain) {
+	features2 := (*C.VkPhysicalDeviceFeatures2)(C.calloc(1, C.sizeof_VkPhysicalDeviceFeatures2))
+	defer C.free(unsafe.Pointer(features2))
+	features2.sType = structureTypePhysicalDeviceFeatures2
+	features2.pNext = chain.head()
+
+	C.vkextGetPhysicalDeviceFeatures2(i.getPhysicalDeviceFeatures2, C.VkPhysicalDevice(unsafe.Pointer(physicalDevice)), features2)
+	chain.load()
+}
+
+// Extensions returns the device extensions that must be enabled for the
+// features set in f on a device with apiVersion, those providing features
+// of newer versions.
+func (f Features) Extensions(apiVersion uint32) []string {
+	var extensions []string
+	for _, promoted := range promotedFeatures {
+		if apiVersion < promoted.version && apiVersion >= promoted.minVersion && *promoted.feature(&f) == vk.True {
+			extensions = append(extensions, promoted.extension)
+		}
+	}
+	return extensions
+}
+
+// DeviceFeatures is a pNext chain for vk.DeviceCreateInfo that enables
+// Features, in C memory.
+type DeviceFeatures struct {
+	chain featureChain
+}
+
+// NewDeviceFeatures builds the structures enabling features on a device
+// with apiVersion and extensions enabled. Chain it with Ref and Free it once
+// the device is created.
+func NewDeviceFeatures(features Features, apiVersion uint32, extensions []string) *DeviceFeatures {
+	d := &DeviceFeatures{}
+	d.chain.build(&features, apiVersion, func(name string) bool {
+		return hasExtension(extensions, name)
+	})
+	d.chain.store()
+	return d
+}
+
+// Ref returns the first structure of the chain, nil when there is none.
+func (d *DeviceFeatures) Ref() unsafe.Pointer {
+	return d.chain.head()
+}
+
+func (d *DeviceFeatures) Free() {
+	d.chain.free()
+}
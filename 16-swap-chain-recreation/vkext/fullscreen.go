@@ -0,0 +1,44 @@
+package vkext
+
+/*
+#include <stdlib.h>
+#include "vkext.h"
+*/
+import "C"
+
+import "unsafe"
+
+const structureTypeSurfaceFullScreenExclusiveInfo = 1000255000
+
+// FullScreenExclusive is VkFullScreenExclusiveEXT, whether a swapchain may
+// take exclusive ownership of the display.
+type FullScreenExclusive int32
+
+const (
+	FullScreenExclusiveDefault    FullScreenExclusive = 0
+	FullScreenExclusiveAllowed    FullScreenExclusive = 1
+	FullScreenExclusiveDisallowed FullScreenExclusive = 2
+)
+
+// SurfaceFullScreenExclusiveInfo is a VkSurfaceFullScreenExclusiveInfoEXT in
+// C memory, to chain into vk.SwapchainCreateInfo.PNext when
+// VK_EXT_full_screen_exclusive is enabled.
+type SurfaceFullScreenExclusiveInfo struct {
+	info *C.VkSurfaceFullScreenExclusiveInfoEXT
+}
+
+func NewSurfaceFullScreenExclusiveInfo(mode FullScreenExclusive) *SurfaceFullScreenExclusiveInfo {
+	info := (*C.VkSurfaceFullScreenExclusiveInfoEXT)(C.calloc(1, C.sizeof_VkSurfaceFullScreenExclusiveInfoEXT))
+	info.sType = structureTypeSurfaceFullScreenExclusiveInfo
+	info.fullScreenExclusive = C.int32_t(mode)
+	return &SurfaceFullScreenExclusiveInfo{info: info}
+}
+
+func (s *SurfaceFullScreenExclusiveInfo) Ref() unsafe.Pointer {
+	return unsafe.Pointer(s.info)
+}
+
+func (s *SurfaceFullScreenExclusiveInfo) Free() {
+	C.free(unsafe.Pointer(s.info))
+	s.info = nil
+}
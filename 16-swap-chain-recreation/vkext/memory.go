@@ -0,0 +1,49 @@
+package vkext
+
+/*
+#include <stdlib.h>
+#include "vkext.h"
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+const (
+	structureTypePhysicalDeviceMemoryProperties2      = 1000059006
+	structureTypePhysicalDeviceMemoryBudgetProperties = 1000237000
+)
+
+// MemoryBudget returns the budget and usage of every memory heap of
+// physicalDevice, which must support VK_EXT_memory_budget, as
+// vkGetPhysicalDeviceMemoryProperties2 reports them. The usage covers the
+// whole process. ok is false when the instance lacks the function.
+func (i *Instance) MemoryBudget(physicalDevice vk.PhysicalDevice) (budget, usage []vk.DeviceSize, ok bool) {
+	if i.getPhysicalDeviceMemoryProperties2 == nil {
+		return nil, nil, false
+	}
+
+	budgetProperties := (*C.VkPhysicalDeviceMemoryBudgetPropertiesEXT)(C.calloc(1, C.sizeof_VkPhysicalDeviceMemoryBudgetPropertiesEXT))
+	defer C.free(unsafe.Pointer(budgetProperties))
+	budgetProperties.sType = structureTypePhysicalDeviceMemoryBudgetProperties
+
+	properties := (*C.VkPhysicalDeviceMemoryProperties2)(C.calloc(1, C.sizeof_VkPhysicalDeviceMemoryProperties2))
+	defer C.free(unsafe.Pointer(properties))
+	properties.sType = structureTypePhysicalDeviceMemoryProperties2
+	properties.pNext = unsafe.Pointer(budgetProperties)
+
+	C.vkextGetPhysicalDeviceMemoryProperties2(i.getPhysicalDeviceMemoryProperties2, C.VkPhysicalDevice(unsafe.Pointer(physicalDevice)), properties)
+
+	heaps := int(properties.memoryHeapCount)
+	budget = make([]vk.DeviceSize, heaps)
+	usage = make([]vk.DeviceSize, heaps)
+	for heap := 0; heap < heaps; heap++ {
+		budget[heap] = vk.DeviceSize(budgetProperties.heapBudget[heap])
+		usage[heap] = vk.DeviceSize(budgetProperties.heapUsage[heap])
+	}
+
+	return budget, usage, true
+}
@@ -0,0 +1,235 @@
+package vkext
+
+/*
+#include <stdlib.h>
+#include "vkext.h"
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+const (
+	structureTypeRenderingInfo                         = 1000044000
+	structureTypeRenderingAttachmentInfo               = 1000044001
+	structureTypePipelineRenderingCreateInfo           = 1000044002
+	structureTypeCommandBufferInheritanceRenderingInfo = 1000044004
+	structureTypeImageMemoryBarrier2                   = 1000314002
+	structureTypeDependencyInfo                        = 1000314003
+)
+
+// RenderingFlags is VkRenderingFlags.
+type RenderingFlags uint32
+
+// RenderingContentsSecondaryCommandBuffers means the rendering is recorded
+// in secondary command buffers executed within it.
+const RenderingContentsSecondaryCommandBuffers RenderingFlags = 0x1
+
+// PipelineStageFlags2 is VkPipelineStageFlags2, 64 bits wide.
+type PipelineStageFlags2 uint64
+
+const (
+	PipelineStage2None                  PipelineStageFlags2 = 0
+	PipelineStage2ColorAttachmentOutput PipelineStageFlags2 = 0x400
+)
+
+// AccessFlags2 is VkAccessFlags2, 64 bits wide.
+type AccessFlags2 uint64
+
+const (
+	Access2None                 AccessFlags2 = 0
+	Access2ColorAttachmentWrite AccessFlags2 = 0x100
+)
+
+// DynamicRendering reports whether the device has the functions of dynamic
+// rendering. Using them also needs the dynamicRendering feature.
+func (d *Device) DynamicRendering() bool {
+	return d.cmdBeginRendering != nil && d.cmdEndRendering != nil
+}
+
+// Synchronization2 reports whether the device has vkCmdPipelineBarrier2.
+// Using it also needs the synchronization2 feature.
+func (d *Device) Synchronization2() bool {
+	return d.cmdPipelineBarrier2 != nil
+}
+
+// RenderingAttachment is VkRenderingAttachmentInfo without resolving.
+type RenderingAttachment struct {
+	ImageView   vk.ImageView
+	ImageLayout vk.ImageLayout
+	LoadOp      vk.AttachmentLoadOp
+	StoreOp     vk.AttachmentStoreOp
+	ClearValue  vk.ClearValue
+}
+
+// RenderingInfo is VkRenderingInfo with color attachments only.
+type RenderingInfo struct {
+	Flags            RenderingFlags
+	RenderArea       vk.Rect2D
+	LayerCount       uint32
+	ColorAttachments []RenderingAttachment
+}
+
+// CmdBeginRendering is vkCmdBeginRendering.
+func (d *Device) CmdBeginRendering(commandBuffer vk.CommandBuffer, info *RenderingInfo) {
+	attachments := (*[1 << 16]C.VkRenderingAttachmentInfo)(C.calloc(C.size_t(len(info.ColorAttachments)+1), C.sizeof_VkRenderingAttachmentInfo))
+	defer C.free(unsafe.Pointer(attachments))
+	for i, attachment := range info.ColorAttachments {
+		attachments[i] = C.VkRenderingAttachmentInfo{
+			sType:       structureTypeRenderingAttachmentInfo,
+			imageView:   handle(unsafe.Pointer(&attachment.ImageView)),
+			imageLayout: C.int32_t(attachment.ImageLayout),
+			loadOp:      C.int32_t(attachment.LoadOp),
+			storeOp:     C.int32_t(attachment.StoreOp),
+			clearValue:  *(*[4]C.uint32_t)(unsafe.Pointer(&attachment.ClearValue)),
+		}
+	}
+
+	renderingInfo := (*C.VkRenderingInfo)(C.calloc(1, C.sizeof_VkRenderingInfo))
+	defer C.free(unsafe.Pointer(renderingInfo))
+	*renderingInfo = C.VkRenderingInfo{
+		sType: structureTypeRenderingInfo,
+		flags: C.uint32_t(info.Flags),
+		renderArea: C.VkRect2D{
+			x:      C.int32_t(info.RenderArea.Offset.X),
+			y:      C.int32_t(info.RenderArea.Offset.Y),
+			width:  C.uint32_t(info.RenderArea.Extent.Width),
+			height: C.uint32_t(info.RenderArea.Extent.Height),
+		},
+		layerCount:           C.uint32_t(info.LayerCount),
+		colorAttachmentCount: C.uint32_t(len(info.ColorAttachments)),
+		pColorAttachments:    &attachments[0],
+	}
+
+	C.vkextCmdBeginRendering(d.cmdBeginRendering, commandBufferHandle(commandBuffer), renderingInfo)
+}
+
+// CmdEndRendering is vkCmdEndRendering.
+func (d *Device) CmdEndRendering(commandBuffer vk.CommandBuffer) {
+	C.vkextCmdEndRendering(d.cmdEndRendering, commandBufferHandle(commandBuffer))
+}
+
+// ImageMemoryBarrier2 is VkImageMemoryBarrier2.
+type ImageMemoryBarrier2 struct {
+	SrcStageMask        PipelineStageFlags2
+	SrcAccessMask       AccessFlags2
+	DstStageMask        PipelineStageFlags2
+	DstAccessMask       AccessFlags2
+	OldLayout           vk.ImageLayout
+	NewLayout           vk.ImageLayout
+	SrcQueueFamilyIndex uint32
+	DstQueueFamilyIndex uint32
+	Image               vk.Image
+	SubresourceRange    vk.ImageSubresourceRange
+}
+
+// CmdPipelineBarrier2 is vkCmdPipelineBarrier2 with image barriers only.
+func (d *Device) CmdPipelineBarrier2(commandBuffer vk.CommandBuffer, dependencyFlags vk.DependencyFlags, imageBarriers []ImageMemoryBarrier2) {
+	barriers := (*[1 << 16]C.VkImageMemoryBarrier2)(C.calloc(C.size_t(len(imageBarriers)+1), C.sizeof_VkImageMemoryBarrier2))
+	defer C.free(unsafe.Pointer(barriers))
+	for i, barrier := range imageBarriers {
+		barriers[i] = C.VkImageMemoryBarrier2{
+			sType:               structureTypeImageMemoryBarrier2,
+			srcStageMask:        C.uint64_t(barrier.SrcStageMask),
+			srcAccessMask:       C.uint64_t(barrier.SrcAccessMask),
+			dstStageMask:        C.uint64_t(barrier.DstStageMask),
+			dstAccessMask:       C.uint64_t(barrier.DstAccessMask),
+			oldLayout:           C.int32_t(barrier.OldLayout),
+			newLayout:           C.int32_t(barrier.NewLayout),
+			srcQueueFamilyIndex: C.uint32_t(barrier.SrcQueueFamilyIndex),
+			dstQueueFamilyIndex: C.uint32_t(barrier.DstQueueFamilyIndex),
+			image:               handle(unsafe.Pointer(&barrier.Image)),
+			subresourceRange: C.VkImageSubresourceRange{
+				aspectMask:     C.uint32_t(barrier.SubresourceRange.AspectMask),
+				baseMipLevel:   C.uint32_t(barrier.SubresourceRange.BaseMipLevel),
+				levelCount:     C.uint32_t(barrier.SubresourceRange.LevelCount),
+				baseArrayLayer: C.uint32_t(barrier.SubresourceRange.BaseArrayLayer),
+				layerCount:     C.uint32_t(barrier.SubresourceRange.LayerCount),
+			},
+		}
+	}
+
+	dependencyInfo := (*C.VkDependencyInfo)(C.calloc(1, C.sizeof_VkDependencyInfo))
+	defer C.free(unsafe.Pointer(dependencyInfo))
+	*dependencyInfo = C.VkDependencyInfo{
+		sType:                   structureTypeDependencyInfo,
+		dependencyFlags:         C.uint32_t(dependencyFlags),
+		imageMemoryBarrierCount: C.uint32_t(len(imageBarriers)),
+		pImageMemoryBarriers:    &barriers[0],
+	}
+
+	C.vkextCmdPipelineBarrier2(d.cmdPipelineBarrier2, commandBufferHandle(commandBuffer), dependencyInfo)
+}
+
+// formats copies formats into C memory, which the caller must free.
+func formats(formats []vk.Format) *C.VkFormat {
+	cFormats := (*[1 << 16]C.VkFormat)(C.calloc(C.size_t(len(formats)+1), C.sizeof_VkFormat))
+	for i, format := range formats {
+		cFormats[i] = C.VkFormat(format)
+	}
+	return &cFormats[0]
+}
+
+// PipelineRenderingCreateInfo is a VkPipelineRenderingCreateInfo in C memory,
+// to chain into vk.GraphicsPipelineCreateInfo.PNext when the pipeline is
+// used with dynamic rendering instead of a render pass.
+type PipelineRenderingCreateInfo struct {
+	info *C.VkPipelineRenderingCreateInfo
+}
+
+// NewPipelineRenderingCreateInfo describes rendering to color attachments of
+// colorFormats.
+func NewPipelineRenderingCreateInfo(colorFormats []vk.Format) *PipelineRenderingCreateInfo {
+	info := (*C.VkPipelineRenderingCreateInfo)(C.calloc(1, C.sizeof_VkPipelineRenderingCreateInfo))
+	info.sType = structureTypePipelineRenderingCreateInfo
+	info.colorAttachmentCount = C.uint32_t(len(colorFormats))
+	info.pColorAttachmentFormats = formats(colorFormats)
+	return &PipelineRenderingCreateInfo{info: info}
+}
+
+func (p *PipelineRenderingCreateInfo) Ref() unsafe.Pointer {
+	return unsafe.Pointer(p.info)
+}
+
+func (p *PipelineRenderingCreateInfo) Free() {
+	C.free(unsafe.Pointer(p.info.pColorAttachmentFormats))
+	C.free(unsafe.Pointer(p.info))
+	p.info = nil
+}
+
+// InheritanceRenderingInfo is a VkCommandBufferInheritanceRenderingInfo in C
+// memory, to chain into vk.CommandBufferInheritanceInfo.PNext for secondary
+// command buffers executed inside dynamic rendering.
+type InheritanceRenderingInfo struct {
+	info *C.VkCommandBufferInheritanceRenderingInfo
+}
+
+// NewInheritanceRenderingInfo describes rendering with flags to color
+// attachments of colorFormats with samples samples.
+func NewInheritanceRenderingInfo(flags RenderingFlags, colorFormats []vk.Format, samples vk.SampleCountFlagBits) *InheritanceRenderingInfo {
+	info := (*C.VkCommandBufferInheritanceRenderingInfo)(C.calloc(1, C.sizeof_VkCommandBufferInheritanceRenderingInfo))
+	info.sType = structureTypeCommandBufferInheritanceRenderingInfo
+	info.flags = C.uint32_t(flags)
+	info.colorAttachmentCount = C.uint32_t(len(colorFormats))
+	info.pColorAttachmentFormats = formats(colorFormats)
+	info.rasterizationSamples = C.uint32_t(samples)
+	return &InheritanceRenderingInfo{info: info}
+}
+
+func (i *InheritanceRenderingInfo) Ref() unsafe.Pointer {
+	return unsafe.Pointer(i.info)
+}
+
+func (i *InheritanceRenderingInfo) Free() {
+	C.free(unsafe.Pointer(i.info.pColorAttachmentFormats))
+	C.free(unsafe.Pointer(i.info))
+	i.info = nil
+}
+
+// commandBufferHandle returns the C handle of commandBuffer.
+func commandBufferHandle(commandBuffer vk.CommandBuffer) C.VkCommandBuffer {
+	return C.VkCommandBuffer(unsafe.Pointer(commandBuffer))
+}
@@ -0,0 +1,105 @@
+package vkext
+
+/*
+#include <stdlib.h>
+#include "vkext.h"
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+const (
+	structureTypeSemaphoreTypeCreateInfo     = 1000207002
+	structureTypeTimelineSemaphoreSubmitInfo = 1000207003
+	semaphoreTypeTimeline                    = 1
+)
+
+// TimelineSemaphores reports whether the device has the functions of
+// timeline semaphores. Using them also needs the timelineSemaphore feature.
+func (d *Device) TimelineSemaphores() bool {
+	return d.waitSemaphores != nil && d.getSemaphoreCounterValue != nil
+}
+
+// WaitSemaphore is vkWaitSemaphores for one timeline semaphore: it blocks
+// until the semaphore reaches value or timeout nanoseconds pass, returning
+// vk.Timeout then.
+func (d *Device) WaitSemaphore(semaphore vk.Semaphore, value, timeout uint64) vk.Result {
+	return vk.Result(C.vkextWaitSemaphore(d.waitSemaphores, d.device, handle(unsafe.Pointer(&semaphore)), C.uint64_t(value), C.uint64_t(timeout)))
+}
+
+// SemaphoreCounterValue is vkGetSemaphoreCounterValue, the current value of
+// a timeline semaphore.
+func (d *Device) SemaphoreCounterValue(semaphore vk.Semaphore) (uint64, vk.Result) {
+	var value C.uint64_t
+	res := vk.Result(C.vkextGetSemaphoreCounterValue(d.getSemaphoreCounterValue, d.device, handle(unsafe.Pointer(&semaphore)), &value))
+	return uint64(value), res
+}
+
+// SemaphoreTypeCreateInfo is a VkSemaphoreTypeCreateInfo in C memory, to
+// chain into vk.SemaphoreCreateInfo.PNext.
+type SemaphoreTypeCreateInfo struct {
+	info *C.VkSemaphoreTypeCreateInfo
+}
+
+// NewTimelineSemaphoreCreateInfo makes vkCreateSemaphore create a timeline
+// semaphore starting at initialValue.
+func NewTimelineSemaphoreCreateInfo(initialValue uint64) *SemaphoreTypeCreateInfo {
+	info := (*C.VkSemaphoreTypeCreateInfo)(C.calloc(1, C.sizeof_VkSemaphoreTypeCreateInfo))
+	info.sType = structureTypeSemaphoreTypeCreateInfo
+	info.semaphoreType = semaphoreTypeTimeline
+	info.initialValue = C.uint64_t(initialValue)
+	return &SemaphoreTypeCreateInfo{info: info}
+}
+
+func (s *SemaphoreTypeCreateInfo) Ref() unsafe.Pointer {
+	return unsafe.Pointer(s.info)
+}
+
+func (s *SemaphoreTypeCreateInfo) Free() {
+	C.free(unsafe.Pointer(s.info))
+	s.info = nil
+}
+
+// TimelineSubmitInfo is a VkTimelineSemaphoreSubmitInfo in C memory, to chain
+// into vk.SubmitInfo.PNext. It can be reused for every submission, the
+// driver reads it during vkQueueSubmit.
+type TimelineSubmitInfo struct {
+	info     *C.VkTimelineSemaphoreSubmitInfo
+	capacity int
+}
+
+func NewTimelineSubmitInfo() *TimelineSubmitInfo {
+	info := (*C.VkTimelineSemaphoreSubmitInfo)(C.calloc(1, C.sizeof_VkTimelineSemaphoreSubmitInfo))
+	info.sType = structureTypeTimelineSemaphoreSubmitInfo
+	return &TimelineSubmitInfo{info: info}
+}
+
+// SetSignalValues sets the value each signalled semaphore of the submission
+// is set to, in order. Values of binary semaphores are ignored.
+func (t *TimelineSubmitInfo) SetSignalValues(values []uint64) {
+	if len(values) > t.capacity {
+		C.free(unsafe.Pointer(t.info.pSignalSemaphoreValues))
+		t.info.pSignalSemaphoreValues = (*C.uint64_t)(C.calloc(C.size_t(len(values)), C.sizeof_uint64_t))
+		t.capacity = len(values)
+	}
+
+	signalValues := (*[1 << 20]C.uint64_t)(unsafe.Pointer(t.info.pSignalSemaphoreValues))[:len(values):len(values)]
+	for i, value := range values {
+		signalValues[i] = C.uint64_t(value)
+	}
+	t.info.signalSemaphoreValueCount = C.uint32_t(len(values))
+}
+
+func (t *TimelineSubmitInfo) Ref() unsafe.Pointer {
+	return unsafe.Pointer(t.info)
+}
+
+func (t *TimelineSubmitInfo) Free() {
+	C.free(unsafe.Pointer(t.info.pSignalSemaphoreValues))
+	C.free(unsafe.Pointer(t.info))
+	t.info = nil
+}
@@ -0,0 +1,60 @@
+#include "vkext.h"
+
+// Go can't call C function pointers, these call them on its behalf.
+
+PFN_vkVoidFunction vkextGetInstanceProcAddr(PFN_vkGetInstanceProcAddr getInstanceProcAddr, VkInstance instance, const char *name) {
+	return getInstanceProcAddr(instance, name);
+}
+
+PFN_vkVoidFunction vkextGetDeviceProcAddr(PFN_vkGetDeviceProcAddr getDeviceProcAddr, VkDevice device, const char *name) {
+	return getDeviceProcAddr(device, name);
+}
+
+VkResult vkextEnumerateInstanceVersion(PFN_vkVoidFunction fn, uint32_t *version) {
+	return ((VkResult (*)(uint32_t *))fn)(version);
+}
+
+void vkextGetPhysicalDeviceFeatures2(PFN_vkVoidFunction fn, VkPhysicalDevice physicalDevice, VkPhysicalDeviceFeatures2 *features) {
+	((void (*)(VkPhysicalDevice, VkPhysicalDeviceFeatures2 *))fn)(physicalDevice, features);
+}
+
+void vkextGetPhysicalDeviceMemoryProperties2(PFN_vkVoidFunction fn, VkPhysicalDevice physicalDevice, VkPhysicalDeviceMemoryProperties2 *properties) {
+	((void (*)(VkPhysicalDevice, VkPhysicalDeviceMemoryProperties2 *))fn)(physicalDevice, properties);
+}
+
+typedef struct VkSemaphoreWaitInfo {
+	VkStructureType sType;
+	const void *pNext;
+	uint32_t flags;
+	uint32_t semaphoreCount;
+	const VkSemaphore *pSemaphores;
+	const uint64_t *pValues;
+} VkSemaphoreWaitInfo;
+
+// The wait info is built here, it points at the semaphore and value, and Go
+// may not pass structures holding pointers to its own memory.
+VkResult vkextWaitSemaphore(PFN_vkVoidFunction fn, VkDevice device, VkSemaphore semaphore, uint64_t value, uint64_t timeout) {
+	VkSemaphoreWaitInfo info = {
+		.sType = 1000207004, // VK_STRUCTURE_TYPE_SEMAPHORE_WAIT_INFO
+		.semaphoreCount = 1,
+		.pSemaphores = &semaphore,
+		.pValues = &value,
+	};
+	return ((VkResult (*)(VkDevice, const VkSemaphoreWaitInfo *, uint64_t))fn)(device, &info, timeout);
+}
+
+VkResult vkextGetSemaphoreCounterValue(PFN_vkVoidFunction fn, VkDevice device, VkSemaphore semaphore, uint64_t *value) {
+	return ((VkResult (*)(VkDevice, VkSemaphore, uint64_t *))fn)(device, semaphore, value);
+}
+
+void vkextCmdBeginRendering(PFN_vkVoidFunction fn, VkCommandBuffer commandBuffer, const VkRenderingInfo *info) {
+	((void (*)(VkCommandBuffer, const VkRenderingInfo *))fn)(commandBuffer, info);
+}
+
+void vkextCmdEndRendering(PFN_vkVoidFunction fn, VkCommandBuffer commandBuffer) {
+	((void (*)(VkCommandBuffer))fn)(commandBuffer);
+}
+
+void vkextCmdPipelineBarrier2(PFN_vkVoidFunction fn, VkCommandBuffer commandBuffer, const VkDependencyInfo *info) {
+	((void (*)(VkCommandBuffer, const VkDependencyInfo *))fn)(commandBuffer, info);
+}
@@ -0,0 +1,166 @@
+// Package vkext calls the Vulkan functions that are newer than the headers
+// the vulkan-go bindings were generated from. They are looked up at run time
+// through vkGetInstanceProcAddr and vkGetDeviceProcAddr, under their core
+// name when the API version has them and under the extension's name
+// otherwise. Functions the driver lacks are reported as unavailable, so
+// callers can keep a fallback.
+//
+// Structures passed to Vulkan are allocated in C memory, those returned to
+// the caller have Free methods.
+package vkext
+
+/*
+#include <stdlib.h>
+#include "vkext.h"
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+const (
+	properties2Extension       = "VK_KHR_get_physical_device_properties2"
+	timelineSemaphoreExtension = "VK_KHR_timeline_semaphore"
+	dynamicRenderingExtension  = "VK_KHR_dynamic_rendering"
+	synchronization2Extension  = "VK_KHR_synchronization2"
+)
+
+var (
+	version11 = vk.MakeVersion(1, 1, 0)
+	version12 = vk.MakeVersion(1, 2, 0)
+	version13 = vk.MakeVersion(1, 3, 0)
+)
+
+var getInstanceProcAddr C.PFN_vkGetInstanceProcAddr
+
+// SetGetInstanceProcAddr sets the vkGetInstanceProcAddr functions are looked
+// up with, the one given to vk.SetGetInstanceProcAddr.
+func SetGetInstanceProcAddr(procAddr unsafe.Pointer) {
+	getInstanceProcAddr = C.PFN_vkGetInstanceProcAddr(procAddr)
+}
+
+func instanceProcAddr(instance C.VkInstance, name string) C.PFN_vkVoidFunction {
+	if getInstanceProcAddr == nil {
+		return nil
+	}
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	return C.vkextGetInstanceProcAddr(getInstanceProcAddr, instance, cName)
+}
+
+// EnumerateInstanceVersion returns the highest Vulkan version the loader
+// supports for instances, made with vk.MakeVersion. Vulkan 1.0 loaders don't
+// have vkEnumerateInstanceVersion, for them it is 1.0.
+func EnumerateInstanceVersion() (uint32, error) {
+	fn := instanceProcAddr(nil, "vkEnumerateInstanceVersion")
+	if fn == nil {
+		return vk.MakeVersion(1, 0, 0), nil
+	}
+
+	var version C.uint32_t
+	res := vk.Result(C.vkextEnumerateInstanceVersion(fn, &version))
+	if res != vk.Success {
+		return 0, vk.Error(res)
+	}
+	return uint32(version), nil
+}
+
+// Instance holds the functions of a vk.Instance.
+type Instance struct {
+	instance                           C.VkInstance
+	apiVersion                         uint32
+	properties2                        bool
+	getPhysicalDeviceFeatures2         C.PFN_vkVoidFunction
+	getPhysicalDeviceMemoryProperties2 C.PFN_vkVoidFunction
+	getDeviceProcAddr                  C.PFN_vkGetDeviceProcAddr
+}
+
+// LoadInstance looks up the functions of instance, which was created with
+// apiVersion and extensions enabled.
+func LoadInstance(instance vk.Instance, apiVersion uint32, extensions []string) *Instance {
+	i := &Instance{
+		instance:    C.VkInstance(unsafe.Pointer(instance)),
+		apiVersion:  apiVersion,
+		properties2: hasExtension(extensions, properties2Extension),
+	}
+	i.getPhysicalDeviceFeatures2 = i.lookup(version11, "vkGetPhysicalDeviceFeatures2", i.properties2)
+	i.getPhysicalDeviceMemoryProperties2 = i.lookup(version11, "vkGetPhysicalDeviceMemoryProperties2", i.properties2)
+	i.getDeviceProcAddr = C.PFN_vkGetDeviceProcAddr(unsafe.Pointer(instanceProcAddr(i.instance, "vkGetDeviceProcAddr")))
+
+	return i
+}
+
+// lookup returns the instance function name when the instance version has
+// it, or its KHR alias when extension, the extension it came from, is
+// enabled. It returns nil when neither is.
+func (i *Instance) lookup(version uint32, name string, extension bool) C.PFN_vkVoidFunction {
+	switch {
+	case i.apiVersion >= version:
+		return instanceProcAddr(i.instance, name)
+	case extension:
+		return instanceProcAddr(i.instance, name+"KHR")
+	default:
+		return nil
+	}
+}
+
+// hasExtension reports whether name is in extensions. Names may carry the
+// terminating NUL the bindings want.
+func hasExtension(extensions []string, name string) bool {
+	for _, extension := range extensions {
+		if strings.TrimSuffix(extension, "\x00") == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Device holds the functions of a vk.Device. Those the device lacks are nil.
+type Device struct {
+	device                   C.VkDevice
+	waitSemaphores           C.PFN_vkVoidFunction
+	getSemaphoreCounterValue C.PFN_vkVoidFunction
+	cmdBeginRendering        C.PFN_vkVoidFunction
+	cmdEndRendering          C.PFN_vkVoidFunction
+	cmdPipelineBarrier2      C.PFN_vkVoidFunction
+}
+
+// LoadDevice looks up the functions of device, created with apiVersion and
+// extensions enabled.
+func (i *Instance) LoadDevice(device vk.Device, apiVersion uint32, extensions []string) *Device {
+	d := &Device{device: C.VkDevice(unsafe.Pointer(device))}
+	if i.getDeviceProcAddr == nil {
+		return d
+	}
+
+	// Like Instance.lookup, with the extensions of the device.
+	lookup := func(version uint32, name, extension string) C.PFN_vkVoidFunction {
+		switch {
+		case apiVersion >= version:
+		case hasExtension(extensions, extension):
+			name += "KHR"
+		default:
+			return nil
+		}
+		cName := C.CString(name)
+		defer C.free(unsafe.Pointer(cName))
+		return C.vkextGetDeviceProcAddr(i.getDeviceProcAddr, d.device, cName)
+	}
+	d.waitSemaphores = lookup(version12, "vkWaitSemaphores", timelineSemaphoreExtension)
+	d.getSemaphoreCounterValue = lookup(version12, "vkGetSemaphoreCounterValue", timelineSemaphoreExtension)
+	d.cmdBeginRendering = lookup(version13, "vkCmdBeginRendering", dynamicRenderingExtension)
+	d.cmdEndRendering = lookup(version13, "vkCmdEndRendering", dynamicRenderingExtension)
+	d.cmdPipelineBarrier2 = lookup(version13, "vkCmdPipelineBarrier2", synchronization2Extension)
+
+	return d
+}
+
+// handle returns the non-dispatchable handle at p, a pointer to a vk.Fence,
+// vk.Semaphore or similar, which are 64 bits on every platform.
+func handle(p unsafe.Pointer) C.uint64_t {
+	return *(*C.uint64_t)(p)
+}
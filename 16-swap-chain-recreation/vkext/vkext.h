@@ -0,0 +1,196 @@
+#ifndef VKEXT_H
+#define VKEXT_H
+
+#include <stdint.h>
+
+// The Vulkan types this package uses, declared here because the headers the
+// vulkan-go bindings were generated from predate most of them. Dispatchable
+// handles are pointers, non-dispatchable ones are 64 bits on every platform.
+
+typedef int32_t VkResult;
+typedef int32_t VkStructureType;
+typedef uint32_t VkBool32;
+typedef uint64_t VkDeviceSize;
+typedef void *VkInstance;
+typedef void *VkPhysicalDevice;
+typedef void *VkDevice;
+typedef uint64_t VkSemaphore;
+typedef void *VkCommandBuffer;
+typedef uint64_t VkImage;
+typedef uint64_t VkImageView;
+typedef int32_t VkFormat;
+
+typedef void (*PFN_vkVoidFunction)(void);
+typedef PFN_vkVoidFunction (*PFN_vkGetInstanceProcAddr)(VkInstance instance, const char *name);
+typedef PFN_vkVoidFunction (*PFN_vkGetDeviceProcAddr)(VkDevice device, const char *name);
+
+// vkextFeatures has the layout of every feature structure: a header and
+// VkBool32 members. The driver only touches as many as its structure has.
+#define VKEXT_MAX_FEATURES 64
+
+typedef struct vkextFeatures {
+	VkStructureType sType;
+	void *pNext;
+	VkBool32 features[VKEXT_MAX_FEATURES];
+} vkextFeatures;
+
+typedef struct VkPhysicalDeviceFeatures2 {
+	VkStructureType sType;
+	void *pNext;
+	// VkPhysicalDeviceFeatures, read with vkGetPhysicalDeviceFeatures.
+	VkBool32 features[55];
+} VkPhysicalDeviceFeatures2;
+
+typedef struct VkSemaphoreTypeCreateInfo {
+	VkStructureType sType;
+	const void *pNext;
+	int32_t semaphoreType;
+	uint64_t initialValue;
+} VkSemaphoreTypeCreateInfo;
+
+typedef struct VkTimelineSemaphoreSubmitInfo {
+	VkStructureType sType;
+	const void *pNext;
+	uint32_t waitSemaphoreValueCount;
+	const uint64_t *pWaitSemaphoreValues;
+	uint32_t signalSemaphoreValueCount;
+	const uint64_t *pSignalSemaphoreValues;
+} VkTimelineSemaphoreSubmitInfo;
+
+typedef struct VkPipelineRenderingCreateInfo {
+	VkStructureType sType;
+	const void *pNext;
+	uint32_t viewMask;
+	uint32_t colorAttachmentCount;
+	const VkFormat *pColorAttachmentFormats;
+	VkFormat depthAttachmentFormat;
+	VkFormat stencilAttachmentFormat;
+} VkPipelineRenderingCreateInfo;
+
+typedef struct VkCommandBufferInheritanceRenderingInfo {
+	VkStructureType sType;
+	const void *pNext;
+	uint32_t flags;
+	uint32_t viewMask;
+	uint32_t colorAttachmentCount;
+	const VkFormat *pColorAttachmentFormats;
+	VkFormat depthAttachmentFormat;
+	VkFormat stencilAttachmentFormat;
+	uint32_t rasterizationSamples;
+} VkCommandBufferInheritanceRenderingInfo;
+
+typedef struct VkRenderingAttachmentInfo {
+	VkStructureType sType;
+	const void *pNext;
+	VkImageView imageView;
+	int32_t imageLayout;
+	uint32_t resolveMode;
+	VkImageView resolveImageView;
+	int32_t resolveImageLayout;
+	int32_t loadOp;
+	int32_t storeOp;
+	// VkClearValue, a union of 16 bytes.
+	uint32_t clearValue[4];
+} VkRenderingAttachmentInfo;
+
+typedef struct VkRect2D {
+	int32_t x;
+	int32_t y;
+	uint32_t width;
+	uint32_t height;
+} VkRect2D;
+
+typedef struct VkRenderingInfo {
+	VkStructureType sType;
+	const void *pNext;
+	uint32_t flags;
+	VkRect2D renderArea;
+	uint32_t layerCount;
+	uint32_t viewMask;
+	uint32_t colorAttachmentCount;
+	const VkRenderingAttachmentInfo *pColorAttachments;
+	const VkRenderingAttachmentInfo *pDepthAttachment;
+	const VkRenderingAttachmentInfo *pStencilAttachment;
+} VkRenderingInfo;
+
+typedef struct VkImageSubresourceRange {
+	uint32_t aspectMask;
+	uint32_t baseMipLevel;
+	uint32_t levelCount;
+	uint32_t baseArrayLayer;
+	uint32_t layerCount;
+} VkImageSubresourceRange;
+
+typedef struct VkImageMemoryBarrier2 {
+	VkStructureType sType;
+	const void *pNext;
+	uint64_t srcStageMask;
+	uint64_t srcAccessMask;
+	uint64_t dstStageMask;
+	uint64_t dstAccessMask;
+	int32_t oldLayout;
+	int32_t newLayout;
+	uint32_t srcQueueFamilyIndex;
+	uint32_t dstQueueFamilyIndex;
+	VkImage image;
+	VkImageSubresourceRange subresourceRange;
+} VkImageMemoryBarrier2;
+
+typedef struct VkDependencyInfo {
+	VkStructureType sType;
+	const void *pNext;
+	uint32_t dependencyFlags;
+	uint32_t memoryBarrierCount;
+	const void *pMemoryBarriers;
+	uint32_t bufferMemoryBarrierCount;
+	const void *pBufferMemoryBarriers;
+	uint32_t imageMemoryBarrierCount;
+	const VkImageMemoryBarrier2 *pImageMemoryBarriers;
+} VkDependencyInfo;
+
+typedef struct VkMemoryType {
+	uint32_t propertyFlags;
+	uint32_t heapIndex;
+} VkMemoryType;
+
+typedef struct VkMemoryHeap {
+	VkDeviceSize size;
+	uint32_t flags;
+} VkMemoryHeap;
+
+typedef struct VkPhysicalDeviceMemoryProperties2 {
+	VkStructureType sType;
+	void *pNext;
+	// VkPhysicalDeviceMemoryProperties.
+	uint32_t memoryTypeCount;
+	VkMemoryType memoryTypes[32];
+	uint32_t memoryHeapCount;
+	VkMemoryHeap memoryHeaps[16];
+} VkPhysicalDeviceMemoryProperties2;
+
+typedef struct VkPhysicalDeviceMemoryBudgetPropertiesEXT {
+	VkStructureType sType;
+	void *pNext;
+	VkDeviceSize heapBudget[16];
+	VkDeviceSize heapUsage[16];
+} VkPhysicalDeviceMemoryBudgetPropertiesEXT;
+
+typedef struct VkSurfaceFullScreenExclusiveInfoEXT {
+	VkStructureType sType;
+	void *pNext;
+	int32_t fullScreenExclusive;
+} VkSurfaceFullScreenExclusiveInfoEXT;
+
+PFN_vkVoidFunction vkextGetInstanceProcAddr(PFN_vkGetInstanceProcAddr getInstanceProcAddr, VkInstance instance, const char *name);
+PFN_vkVoidFunction vkextGetDeviceProcAddr(PFN_vkGetDeviceProcAddr getDeviceProcAddr, VkDevice device, const char *name);
+VkResult vkextEnumerateInstanceVersion(PFN_vkVoidFunction fn, uint32_t *version);
+void vkextGetPhysicalDeviceFeatures2(PFN_vkVoidFunction fn, VkPhysicalDevice physicalDevice, VkPhysicalDeviceFeatures2 *features);
+void vkextGetPhysicalDeviceMemoryProperties2(PFN_vkVoidFunction fn, VkPhysicalDevice physicalDevice, VkPhysicalDeviceMemoryProperties2 *properties);
+VkResult vkextWaitSemaphore(PFN_vkVoidFunction fn, VkDevice device, VkSemaphore semaphore, uint64_t value, uint64_t timeout);
+VkResult vkextGetSemaphoreCounterValue(PFN_vkVoidFunction fn, VkDevice device, VkSemaphore semaphore, uint64_t *value);
+
+void vkextCmdBeginRendering(PFN_vkVoidFunction fn, VkCommandBuffer commandBuffer, const VkRenderingInfo *info);
+void vkextCmdEndRendering(PFN_vkVoidFunction fn, VkCommandBuffer commandBuffer);
+void vkextCmdPipelineBarrier2(PFN_vkVoidFunction fn, VkCommandBuffer commandBuffer, const VkDependencyInfo *info);
+
+#endif
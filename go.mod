@@ -1,6 +1,6 @@
 module vulkan-tutorial-go
 
-go 1.15
+go 1.16
 
 require (
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20201108214237-06ea97f0c265